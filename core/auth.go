@@ -0,0 +1,231 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/pbkdf2"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	ANONYMOUS   = "anonymous"
+	TICKET      = "ticket"
+	WAMPCRA     = "wampcra"
+	COOKIE      = "cookie"
+	authTimeout = time.Second * 5
+)
+
+// Authenticator is an authmethod plugin on router authentication chain
+type Authenticator interface {
+	AuthMethod() string
+	// Challenge builds CHALLENGE message, nil challenge authenticates on HELLO
+	Challenge(h *Hello, p Peer, sessionID ID) (*Challenge, error)
+	// Authenticate validates AUTHENTICATE response, nil if no challenge was sent
+	Authenticate(h *Hello, p Peer, c *Challenge, a *Authenticate) (*Identity, error)
+}
+
+// Identity is the authenticated session identity
+type Identity struct {
+	AuthID       string
+	AuthRole     string
+	AuthMethod   string
+	AuthProvider string
+	Extra        map[string]interface{}
+}
+
+func (i *Identity) details() map[string]interface{} {
+	d := map[string]interface{}{
+		"authid":       i.AuthID,
+		"authrole":     i.AuthRole,
+		"authmethod":   i.AuthMethod,
+		"authprovider": i.AuthProvider,
+	}
+	if len(i.Extra) > 0 {
+		d["authextra"] = i.Extra
+	}
+
+	return d
+}
+
+// authError aborts session establishment with reason
+type authError struct {
+	reason  URI
+	message string
+}
+
+func (e *authError) Error() string {
+	return e.message
+}
+
+func (e *authError) abort() *Abort {
+	return &Abort{
+		Details: map[string]interface{}{"message": e.message},
+		Reason:  e.reason,
+	}
+}
+
+// Credentials holds WAMP-CRA authid secret, on salted credentials
+// Secret is the PBKDF2 derived key (see DeriveKey)
+type Credentials struct {
+	AuthID     string `json:"authid"`
+	AuthRole   string `json:"authrole"`
+	Secret     string `json:"secret"`
+	Salt       string `json:"salt"`
+	Iterations int    `json:"iterations"`
+	KeyLen     int    `json:"keylen"`
+}
+
+// CredentialStore provides WAMP-CRA credentials by authid
+type CredentialStore interface {
+	Credentials(authID string) (*Credentials, error)
+}
+
+// TicketValidator checks authid ticket returning its identity
+type TicketValidator interface {
+	ValidateTicket(authID, ticket string) (*Identity, error)
+}
+
+// CookieValidator resolves identity from authentication cookie value
+type CookieValidator interface {
+	ValidateCookie(value string) (*Identity, error)
+}
+
+type staticCredentialStore struct {
+	credentials map[string]*Credentials
+	mutex       *sync.RWMutex
+}
+
+func NewStaticCredentialStore(credentials ...*Credentials) *staticCredentialStore {
+	s := &staticCredentialStore{
+		credentials: make(map[string]*Credentials),
+		mutex:       &sync.RWMutex{},
+	}
+	for _, c := range credentials {
+		s.Add(c)
+	}
+
+	return s
+}
+
+func (s *staticCredentialStore) Add(c *Credentials) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.credentials[c.AuthID] = c
+}
+
+func (s *staticCredentialStore) Credentials(authID string) (*Credentials, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	c, ok := s.credentials[authID]
+	if !ok {
+		return nil, fmt.Errorf("authid %s not found", authID)
+	}
+
+	return c, nil
+}
+
+// ValidateTicket compares ticket with authid secret
+func (s *staticCredentialStore) ValidateTicket(authID, ticket string) (*Identity, error) {
+	c, err := s.Credentials(authID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal([]byte(c.Secret), []byte(ticket)) {
+		return nil, fmt.Errorf("Invalid ticket for authid %s", authID)
+	}
+
+	return &Identity{AuthID: c.AuthID, AuthRole: c.AuthRole}, nil
+}
+
+// ValidateCookie finds credentials whose secret is the cookie value
+func (s *staticCredentialStore) ValidateCookie(value string) (*Identity, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, c := range s.credentials {
+		if value != "" && hmac.Equal([]byte(c.Secret), []byte(value)) {
+			return &Identity{AuthID: c.AuthID, AuthRole: c.AuthRole}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Invalid cookie")
+}
+
+// DeriveKey computes a salted WAMP-CRA secret as Autobahn clients do
+func DeriveKey(secret, salt string, iterations, keyLen int) string {
+	key := pbkdf2.Key([]byte(secret), []byte(salt), iterations, keyLen, sha256.New)
+
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// SignChallenge computes WAMP-CRA signature from challenge and secret
+func SignChallenge(challenge, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(challenge))
+
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func nonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Println("Error generating nonce ", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// authMethods returns HELLO authmethods, anonymous if none requested
+func authMethods(h *Hello) []string {
+	methods := []string{}
+	list, ok := h.Details["authmethods"].([]interface{})
+	if !ok {
+		return []string{ANONYMOUS}
+	}
+	for _, m := range list {
+		if method, ok := m.(string); ok {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods
+}
+
+func hasAuthMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// waitAuthenticate waits AUTHENTICATE response to a sent challenge
+func waitAuthenticate(p Peer) (*Authenticate, error) {
+	timeout := time.NewTimer(authTimeout)
+	defer timeout.Stop()
+	select {
+	case msg, open := <-p.Receive():
+		if !open {
+			return nil, &authError{URI("wamp.error.authentication_failed"), "Peer closed on authentication"}
+		}
+		authenticate, ok := msg.(*Authenticate)
+		if !ok {
+			err := fmt.Sprintf("Unexpected type on authenticate: %d", msg.MsgType())
+			return nil, &authError{URI("wamp.error.authentication_failed"), err}
+		}
+
+		return authenticate, nil
+	case <-timeout.C:
+		return nil, &authError{URI("wamp.error.authentication_failed"), "Timeout waiting Authenticate Message"}
+	}
+}
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestWampCRAAuthentication(t *testing.T) {
+	r := NewRouter(DefaultConfig())
+	defer r.Terminate()
+	r.SetAuthenticators(NewCRAAuthenticator(NewStaticCredentialStore(&Credentials{
+		AuthID:   "joe",
+		AuthRole: "frontend",
+		Secret:   "secret2",
+	})))
+
+	p := NewPipePeer(PeerID("123"))
+	p.in <- &Hello{
+		Realm: defaultRealm,
+		Details: map[string]interface{}{
+			"authmethods": []interface{}{WAMPCRA},
+			"authid":      "joe",
+		},
+	}
+	done := make(chan error)
+	go func() {
+		done <- r.Accept(p)
+	}()
+
+	msg := <-p.out
+	c, ok := msg.(*Challenge)
+	if !ok {
+		t.Fatal("Unexpected challenge response ", msg.MsgType())
+	}
+	challenge := c.Extra["challenge"].(string)
+	info := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(challenge), &info); err != nil {
+		t.Fatal("Unexpected challenge format ", err)
+	}
+	if info["authid"] != "joe" || info["nonce"] == "" {
+		t.Error("Unexpected challenge content ", info)
+	}
+
+	p.in <- &Authenticate{Signature: SignChallenge(challenge, "secret2")}
+	msg = <-p.out
+	w, ok := msg.(*Welcome)
+	if !ok {
+		t.Fatal("Unexpected welcome response ", msg.MsgType())
+	}
+	if w.Details["authid"] != "joe" || w.Details["authrole"] != "frontend" || w.Details["authmethod"] != WAMPCRA {
+		t.Error("Unexpected welcome details ", w.Details)
+	}
+	if err := <-done; err != nil {
+		t.Error("Unexpected error on accept ", err)
+	}
+	p.in <- &Goodbye{}
+}
+
+func TestWampCRAInvalidSignature(t *testing.T) {
+	r := NewRouter(DefaultConfig())
+	defer r.Terminate()
+	r.SetAuthenticators(NewCRAAuthenticator(NewStaticCredentialStore(&Credentials{
+		AuthID:     "joe",
+		AuthRole:   "frontend",
+		Secret:     DeriveKey("secret2", "salt123", 100, 16),
+		Salt:       "salt123",
+		Iterations: 100,
+		KeyLen:     16,
+	})))
+
+	p := NewPipePeer(PeerID("123"))
+	p.in <- &Hello{
+		Realm: defaultRealm,
+		Details: map[string]interface{}{
+			"authmethods": []interface{}{WAMPCRA},
+			"authid":      "joe",
+		},
+	}
+	go r.Accept(p)
+
+	c := (<-p.out).(*Challenge)
+	if c.Extra["salt"] != "salt123" || c.Extra["iterations"] != 100 || c.Extra["keylen"] != 16 {
+		t.Error("Unexpected salted challenge extra ", c.Extra)
+	}
+
+	// sign with raw secret instead of derived key
+	p.in <- &Authenticate{Signature: SignChallenge(c.Extra["challenge"].(string), "secret2")}
+	msg := <-p.out
+	a, ok := msg.(*Abort)
+	if !ok {
+		t.Fatal("Unexpected abort response ", msg.MsgType())
+	}
+	if a.Reason != URI("wamp.error.authentication_failed") {
+		t.Error("Unexpected abort reason ", a.Reason)
+	}
+}
+
+func TestDeriveKey(t *testing.T) {
+	if DeriveKey("secret", "salt", 1000, 32) == DeriveKey("secret", "salt2", 1000, 32) {
+		t.Error("Derived keys must depend on salt")
+	}
+	if len(DeriveKey("secret", "salt", 1000, 32)) != 44 {
+		t.Error("Unexpected derived key length")
+	}
+}
+
+func TestTicketAuthenticationOnRealmChain(t *testing.T) {
+	r := NewRouter(&Config{Realms: []RealmConfig{
+		{Name: URI("dev"), AuthMethods: []string{ANONYMOUS, TICKET}},
+		{Name: URI("production"), AuthMethods: []string{TICKET}},
+	}})
+	defer r.Terminate()
+	store := NewStaticCredentialStore(&Credentials{AuthID: "gateway", AuthRole: "browser", Secret: "token"})
+	r.SetAuthenticators(NewTicketAuthenticator(store), NewAnonymousAuthenticator(ANONYMOUS))
+
+	p := NewPipePeer(PeerID("123"))
+	p.in <- &Hello{
+		Realm: URI("production"),
+		Details: map[string]interface{}{
+			"authmethods": []interface{}{TICKET},
+			"authid":      "gateway",
+		},
+	}
+	go r.Accept(p)
+
+	msg := <-p.out
+	if c, ok := msg.(*Challenge); !ok || c.AuthMethod != TICKET {
+		t.Fatal("Unexpected ticket challenge ", msg.MsgType())
+	}
+	p.in <- &Authenticate{Signature: "token"}
+	msg = <-p.out
+	w, ok := msg.(*Welcome)
+	if !ok {
+		t.Fatal("Unexpected welcome response ", msg.MsgType())
+	}
+	if w.Details["authid"] != "gateway" || w.Details["authrole"] != "browser" || w.Details["authmethod"] != TICKET {
+		t.Error("Unexpected welcome details ", w.Details)
+	}
+	p.in <- &Goodbye{}
+
+	// anonymous access is not allowed on production realm
+	p = NewPipePeer(PeerID("1234"))
+	p.in <- &Hello{Realm: URI("production"), Details: map[string]interface{}{}}
+	r.Accept(p)
+	msg = <-p.out
+	a, ok := msg.(*Abort)
+	if !ok {
+		t.Fatal("Unexpected abort response ", msg.MsgType())
+	}
+	if a.Reason != URI("wamp.error.no_auth_method") {
+		t.Error("Unexpected abort reason ", a.Reason)
+	}
+
+	// anonymous access on dev realm
+	p = NewPipePeer(PeerID("12345"))
+	p.in <- &Hello{Realm: URI("dev"), Details: map[string]interface{}{}}
+	r.Accept(p)
+	msg = <-p.out
+	w, ok = msg.(*Welcome)
+	if !ok {
+		t.Fatal("Unexpected welcome response ", msg.MsgType())
+	}
+	if w.Details["authmethod"] != ANONYMOUS {
+		t.Error("Unexpected welcome authmethod ", w.Details)
+	}
+	p.in <- &Goodbye{}
+}
+
+type cookiePipePeer struct {
+	*pipePeer
+	request *http.Request
+}
+
+func (p *cookiePipePeer) Request() *http.Request {
+	return p.request
+}
+
+type cookieIdentities map[string]*Identity
+
+func (c cookieIdentities) ValidateCookie(value string) (*Identity, error) {
+	identity, ok := c[value]
+	if !ok {
+		return nil, fmt.Errorf("Invalid cookie %s", value)
+	}
+
+	return &Identity{AuthID: identity.AuthID, AuthRole: identity.AuthRole}, nil
+}
+
+func TestCookieAuthenticationOnClientAuthMethodsOrder(t *testing.T) {
+	r := NewRouter(DefaultConfig())
+	defer r.Terminate()
+	cookies := cookieIdentities{"abc": &Identity{AuthID: "joe", AuthRole: "frontend"}}
+	r.SetAuthenticators(NewAnonymousAuthenticator(ANONYMOUS), NewCookieAuthenticator(cookies))
+
+	accept := func(id PeerID, cookie string, methods ...interface{}) Message {
+		req, _ := http.NewRequest("GET", "http://localhost/", nil)
+		if cookie != "" {
+			req.AddCookie(&http.Cookie{Name: authCookieName, Value: cookie})
+		}
+		p := &cookiePipePeer{NewPipePeer(id), req}
+		p.in <- &Hello{Realm: defaultRealm, Details: map[string]interface{}{"authmethods": methods}}
+		r.Accept(p)
+		msg := <-p.out
+		p.in <- &Goodbye{}
+
+		return msg
+	}
+
+	// client prefers cookie over anonymous
+	w, ok := accept(PeerID("1"), "abc", COOKIE, ANONYMOUS).(*Welcome)
+	if !ok || w.Details["authmethod"] != COOKIE || w.Details["authid"] != "joe" || w.Details["authrole"] != "frontend" {
+		t.Error("Unexpected cookie welcome ", w)
+	}
+
+	// falls back to anonymous without cookie
+	w, ok = accept(PeerID("2"), "", COOKIE, ANONYMOUS).(*Welcome)
+	if !ok || w.Details["authmethod"] != ANONYMOUS {
+		t.Error("Unexpected fallback welcome ", w)
+	}
+
+	a, ok := accept(PeerID("3"), "", COOKIE).(*Abort)
+	if !ok || a.Reason != URI("wamp.error.authentication_failed") {
+		t.Error("Unexpected abort without cookie ", a)
+	}
+	a, ok = accept(PeerID("4"), "unknown", COOKIE).(*Abort)
+	if !ok || a.Reason != URI("wamp.error.authentication_failed") {
+		t.Error("Unexpected abort on invalid cookie ", a)
+	}
+}
+
+func TestAuthenticatorsConfig(t *testing.T) {
+	r := NewRouter(&Config{
+		Authenticators: []AuthenticatorConfig{
+			{AuthMethod: TICKET, Credentials: []*Credentials{{AuthID: "root", AuthRole: "admin", Secret: "token"}}},
+			{AuthMethod: ANONYMOUS, Role: "guest"},
+		},
+		Realms: []RealmConfig{{Name: defaultRealm}},
+	})
+	defer r.Terminate()
+
+	p := NewPipePeer(PeerID("123"))
+	p.in <- &Hello{
+		Realm: defaultRealm,
+		Details: map[string]interface{}{
+			"authmethods": []interface{}{TICKET},
+			"authid":      "root",
+		},
+	}
+	go r.Accept(p)
+	if msg := <-p.out; msg.MsgType() != CHALLENGE {
+		t.Fatal("Unexpected ticket challenge ", msg.MsgType())
+	}
+	p.in <- &Authenticate{Signature: "token"}
+	w, ok := (<-p.out).(*Welcome)
+	if !ok || w.Details["authrole"] != "admin" {
+		t.Fatal("Unexpected admin welcome ", w)
+	}
+	p.in <- &Goodbye{}
+
+	p = NewPipePeer(PeerID("1234"))
+	p.in <- &Hello{Realm: defaultRealm, Details: map[string]interface{}{}}
+	r.Accept(p)
+	w, ok = (<-p.out).(*Welcome)
+	if !ok || w.Details["authrole"] != "guest" {
+		t.Error("Unexpected anonymous welcome ", w)
+	}
+	p.in <- &Goodbye{}
+
+	if _, err := NewAuthenticators([]AuthenticatorConfig{{AuthMethod: "unknown"}}); err == nil {
+		t.Error("Expected error on unknown authmethod")
+	}
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const authCookieName = "cbtid"
+
+/** Anonymous authenticator, accepts any session without challenge **/
+type anonymousAuthenticator struct {
+	role string
+}
+
+func NewAnonymousAuthenticator(role string) *anonymousAuthenticator {
+	return &anonymousAuthenticator{role: role}
+}
+
+func (a *anonymousAuthenticator) AuthMethod() string {
+	return ANONYMOUS
+}
+
+func (a *anonymousAuthenticator) Challenge(h *Hello, p Peer, sessionID ID) (*Challenge, error) {
+	return nil, nil
+}
+
+func (a *anonymousAuthenticator) Authenticate(h *Hello, p Peer, c *Challenge, auth *Authenticate) (*Identity, error) {
+	return &Identity{
+		AuthID:       string(p.ID()),
+		AuthRole:     a.role,
+		AuthMethod:   ANONYMOUS,
+		AuthProvider: "static",
+	}, nil
+}
+
+/** Ticket authenticator, AUTHENTICATE signature carries the ticket **/
+type ticketAuthenticator struct {
+	validator TicketValidator
+}
+
+func NewTicketAuthenticator(v TicketValidator) *ticketAuthenticator {
+	return &ticketAuthenticator{validator: v}
+}
+
+func (a *ticketAuthenticator) AuthMethod() string {
+	return TICKET
+}
+
+func (a *ticketAuthenticator) Challenge(h *Hello, p Peer, sessionID ID) (*Challenge, error) {
+	return &Challenge{AuthMethod: TICKET, Extra: map[string]interface{}{}}, nil
+}
+
+func (a *ticketAuthenticator) Authenticate(h *Hello, p Peer, c *Challenge, auth *Authenticate) (*Identity, error) {
+	authID, _ := h.Details["authid"].(string)
+	identity, err := a.validator.ValidateTicket(authID, auth.Signature)
+	if err != nil {
+		return nil, &authError{URI("wamp.error.authentication_failed"), err.Error()}
+	}
+	if identity.AuthID == "" {
+		identity.AuthID = authID
+	}
+	identity.AuthMethod = TICKET
+	identity.AuthProvider = "static"
+
+	return identity, nil
+}
+
+/** WAMP-CRA authenticator, HMAC-SHA256 signed challenge **/
+type craAuthenticator struct {
+	store CredentialStore
+}
+
+func NewCRAAuthenticator(s CredentialStore) *craAuthenticator {
+	return &craAuthenticator{store: s}
+}
+
+func (a *craAuthenticator) AuthMethod() string {
+	return WAMPCRA
+}
+
+func (a *craAuthenticator) Challenge(h *Hello, p Peer, sessionID ID) (*Challenge, error) {
+	authID, _ := h.Details["authid"].(string)
+	credentials, err := a.store.Credentials(authID)
+	if err != nil {
+		return nil, &authError{URI("wamp.error.not_authorized"), err.Error()}
+	}
+
+	info := map[string]interface{}{
+		"authid":       credentials.AuthID,
+		"authrole":     credentials.AuthRole,
+		"authmethod":   WAMPCRA,
+		"authprovider": "static",
+		"nonce":        nonce(),
+		"timestamp":    time.Now().UTC().Format(time.RFC3339Nano),
+		"session":      sessionID,
+	}
+	challenge, err := json.Marshal(info)
+	if err != nil {
+		return nil, &authError{URI("wamp.error.authentication_failed"), err.Error()}
+	}
+
+	extra := map[string]interface{}{"challenge": string(challenge)}
+	if credentials.Salt != "" {
+		extra["salt"] = credentials.Salt
+		extra["iterations"] = credentials.Iterations
+		extra["keylen"] = credentials.KeyLen
+	}
+
+	return &Challenge{AuthMethod: WAMPCRA, Extra: extra}, nil
+}
+
+func (a *craAuthenticator) Authenticate(h *Hello, p Peer, c *Challenge, auth *Authenticate) (*Identity, error) {
+	authID, _ := h.Details["authid"].(string)
+	credentials, err := a.store.Credentials(authID)
+	if err != nil {
+		return nil, &authError{URI("wamp.error.not_authorized"), err.Error()}
+	}
+
+	challenge, _ := c.Extra["challenge"].(string)
+	expected := SignChallenge(challenge, credentials.Secret)
+	if !hmac.Equal([]byte(expected), []byte(auth.Signature)) {
+		return nil, &authError{URI("wamp.error.authentication_failed"), "Invalid signature"}
+	}
+
+	return &Identity{
+		AuthID:       credentials.AuthID,
+		AuthRole:     credentials.AuthRole,
+		AuthMethod:   WAMPCRA,
+		AuthProvider: "static",
+	}, nil
+}
+
+/** Cookie authenticator, identity from transport cookie **/
+type cookieAuthenticator struct {
+	validator CookieValidator
+}
+
+func NewCookieAuthenticator(v CookieValidator) *cookieAuthenticator {
+	return &cookieAuthenticator{validator: v}
+}
+
+func (a *cookieAuthenticator) AuthMethod() string {
+	return COOKIE
+}
+
+func (a *cookieAuthenticator) Challenge(h *Hello, p Peer, sessionID ID) (*Challenge, error) {
+	return nil, nil
+}
+
+func (a *cookieAuthenticator) Authenticate(h *Hello, p Peer, c *Challenge, auth *Authenticate) (*Identity, error) {
+	hp, ok := p.(HTTPPeer)
+	if !ok || hp.Request() == nil {
+		return nil, &authError{URI("wamp.error.authentication_failed"), "Cookie not available on transport"}
+	}
+
+	cookie, err := hp.Request().Cookie(authCookieName)
+	if err != nil {
+		return nil, &authError{URI("wamp.error.authentication_failed"), err.Error()}
+	}
+
+	identity, err := a.validator.ValidateCookie(cookie.Value)
+	if err != nil {
+		return nil, &authError{URI("wamp.error.authentication_failed"), err.Error()}
+	}
+	identity.AuthMethod = COOKIE
+	identity.AuthProvider = "cookie"
+
+	return identity, nil
+}
+
+// AuthenticatorConfig defines a router authentication chain plugin,
+// Role is anonymous authrole and tls default authrole, Roles maps tls
+// certificate common names to authroles, ticket, wampcra and cookie
+// authmethods validate against Credentials, cookie value is its secret
+type AuthenticatorConfig struct {
+	AuthMethod  string            `json:"authmethod"`
+	Role        string            `json:"role"`
+	Roles       map[string]string `json:"roles"`
+	Credentials []*Credentials    `json:"credentials"`
+}
+
+// NewAuthenticators builds ordered authentication chain from config
+func NewAuthenticators(configs []AuthenticatorConfig) ([]Authenticator, error) {
+	chain := []Authenticator{}
+	for _, c := range configs {
+		switch c.AuthMethod {
+		case ANONYMOUS:
+			role := c.Role
+			if role == "" {
+				role = ANONYMOUS
+			}
+			chain = append(chain, NewAnonymousAuthenticator(role))
+		case TICKET:
+			chain = append(chain, NewTicketAuthenticator(NewStaticCredentialStore(c.Credentials...)))
+		case WAMPCRA:
+			chain = append(chain, NewCRAAuthenticator(NewStaticCredentialStore(c.Credentials...)))
+		case COOKIE:
+			chain = append(chain, NewCookieAuthenticator(NewStaticCredentialStore(c.Credentials...)))
+		case TLS:
+			chain = append(chain, NewTLSAuthenticator(NewSubjectMapper(c.Roles, c.Role)))
+		default:
+			return nil, fmt.Errorf("Unknown authmethod %s on authenticators", c.AuthMethod)
+		}
+	}
+
+	return chain, nil
+}
+
+func unsupportedAuthMethods(methods []string) error {
+	return &authError{
+		URI("wamp.error.no_auth_method"),
+		fmt.Sprintf("No authenticator found for authmethods %v", methods),
+	}
+}
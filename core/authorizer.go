@@ -0,0 +1,187 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type Action string
+
+const (
+	ActionPublish   Action = "publish"
+	ActionSubscribe Action = "subscribe"
+	ActionCall      Action = "call"
+	ActionRegister  Action = "register"
+	ANY                    = "*"
+	ADMIN                  = "admin"
+)
+
+// Authorizer decides if a role can perform action on uri
+type Authorizer interface {
+	Authorize(role string, uri URI, action Action) bool
+}
+
+// Rule matches role, uri and action, "*" role or action matches any,
+// Deny rules reject matched requests
+type Rule struct {
+	Role   string `json:"role"`
+	URI    URI    `json:"uri"`
+	Match  string `json:"match"`
+	Action Action `json:"action"`
+	Deny   bool   `json:"deny"`
+}
+
+func (r *Rule) matches(role string, uri URI, action Action) bool {
+	if r.Role != ANY && r.Role != role {
+		return false
+	}
+	if r.Action != Action(ANY) && r.Action != action {
+		return false
+	}
+
+	return matchURI(r.Match, string(r.URI), string(uri))
+}
+
+// DefaultRules allows everything but wampire procedures and session kill
+// meta procedures, restricted to admin role, and registering or publishing
+// on reserved wamp uris
+func DefaultRules() []*Rule {
+	return []*Rule{
+		{Role: ADMIN, URI: URI("wampire."), Match: PREFIX, Action: Action(ANY)},
+		{Role: ANY, URI: URI("wampire."), Match: PREFIX, Action: ActionCall, Deny: true},
+		{Role: ANY, URI: URI("wampire."), Match: PREFIX, Action: ActionRegister, Deny: true},
+		{Role: ADMIN, URI: URI("wamp.session.kill"), Match: PREFIX, Action: ActionCall},
+		{Role: ANY, URI: URI("wamp.session.kill"), Match: PREFIX, Action: ActionCall, Deny: true},
+		{Role: ANY, URI: URI("wamp."), Match: PREFIX, Action: ActionRegister, Deny: true},
+		{Role: ANY, URI: URI("wamp."), Match: PREFIX, Action: ActionPublish, Deny: true},
+		{Role: ANY, URI: URI(""), Match: PREFIX, Action: Action(ANY)},
+	}
+}
+
+// ruleAuthorizer evaluates rules in order, first matching rule wins,
+// requests without matching rule are denied
+type ruleAuthorizer struct {
+	rules   []*Rule
+	path    string
+	modTime time.Time
+	mutex   *sync.RWMutex
+	done    chan struct{}
+}
+
+func NewRuleAuthorizer(rules []*Rule) *ruleAuthorizer {
+	return &ruleAuthorizer{
+		rules: rules,
+		mutex: &sync.RWMutex{},
+		done:  make(chan struct{}),
+	}
+}
+
+// LoadRuleAuthorizer reads json rules list from file
+func LoadRuleAuthorizer(path string) (*ruleAuthorizer, error) {
+	a := NewRuleAuthorizer(nil)
+	a.path = path
+	if err := a.reload(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *ruleAuthorizer) Authorize(role string, uri URI, action Action) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	for _, r := range a.rules {
+		if r.matches(role, uri, action) {
+			return !r.Deny
+		}
+	}
+
+	return false
+}
+
+// Watch reloads rules file on changes until Terminate
+func (a *ruleAuthorizer) Watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			info, err := os.Stat(a.path)
+			if err != nil {
+				log.Println("Error checking authorization rules ", err)
+				continue
+			}
+			a.mutex.RLock()
+			changed := info.ModTime() != a.modTime
+			a.mutex.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := a.reload(); err != nil {
+				log.Println("Error reloading authorization rules, keep previous ones ", err)
+				continue
+			}
+			log.Println("Authorization rules reloaded from ", a.path)
+		case <-a.done:
+			return
+		}
+	}
+}
+
+func (a *ruleAuthorizer) Terminate() {
+	close(a.done)
+}
+
+func (a *ruleAuthorizer) reload() error {
+	info, err := os.Stat(a.path)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(a.path)
+	if err != nil {
+		return err
+	}
+
+	rules := []*Rule{}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("Error parsing authorization rules %s: %s", a.path, err)
+	}
+	for _, r := range rules {
+		if r.Match != "" && !validMatchPolicy(r.Match) {
+			return fmt.Errorf("Invalid match policy %s on rule %s", r.Match, r.URI)
+		}
+	}
+
+	a.mutex.Lock()
+	a.rules = rules
+	a.modTime = info.ModTime()
+	a.mutex.Unlock()
+
+	return nil
+}
+
+// authorized checks session role, internal session is always trusted
+func authorized(a Authorizer, s *Session, uri URI, action Action) bool {
+	if a == nil || s.ID() == PeerID("internal") {
+		return true
+	}
+
+	return a.Authorize(s.authRole(), uri, action)
+}
+
+func notAuthorized(t MsgType, request ID, uri URI) *Error {
+	return &Error{
+		Type:    t,
+		Request: request,
+		Details: map[string]interface{}{"uri": uri},
+		Error:   URI("wamp.error.not_authorized"),
+	}
+}
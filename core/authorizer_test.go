@@ -0,0 +1,116 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefaultRulesRestrictWampireProcedures(t *testing.T) {
+	a := NewRuleAuthorizer(DefaultRules())
+
+	if a.Authorize(ANONYMOUS, URI("wampire.core.dealer.dump"), ActionCall) {
+		t.Error("Unexpected anonymous call authorization on wampire procedures")
+	}
+	if a.Authorize(ANONYMOUS, URI("wampire.foo"), ActionRegister) {
+		t.Error("Unexpected anonymous register authorization on wampire procedures")
+	}
+	if !a.Authorize(ADMIN, URI("wampire.core.dealer.dump"), ActionCall) {
+		t.Error("Unexpected admin call denial on wampire procedures")
+	}
+	if !a.Authorize(ANONYMOUS, URI("com.myapp.foo"), ActionCall) {
+		t.Error("Unexpected anonymous call denial")
+	}
+	if a.Authorize(ANONYMOUS, URI("wamp.session.kill_all"), ActionCall) || !a.Authorize(ADMIN, URI("wamp.session.kill_all"), ActionCall) {
+		t.Error("Unexpected session kill authorization")
+	}
+	if !a.Authorize(ANONYMOUS, URI("wamp.session.list"), ActionCall) {
+		t.Error("Unexpected anonymous session meta procedure denial")
+	}
+	if a.Authorize(ANONYMOUS, URI("wamp.subscription.on_create"), ActionPublish) {
+		t.Error("Unexpected anonymous publish authorization on meta event topics")
+	}
+}
+
+func TestRuleMatchPolicies(t *testing.T) {
+	a := NewRuleAuthorizer([]*Rule{
+		{Role: "backend", URI: URI("com.myapp.add"), Match: EXACT, Action: ActionRegister},
+		{Role: "frontend", URI: URI("com.myapp."), Match: PREFIX, Action: ActionCall},
+		{Role: "frontend", URI: URI("com..update"), Match: WILDCARD, Action: ActionSubscribe},
+	})
+
+	if !a.Authorize("backend", URI("com.myapp.add"), ActionRegister) {
+		t.Error("Unexpected denial on exact rule")
+	}
+	if a.Authorize("backend", URI("com.myapp.add2"), ActionRegister) {
+		t.Error("Unexpected authorization on exact rule")
+	}
+	if !a.Authorize("frontend", URI("com.myapp.add"), ActionCall) {
+		t.Error("Unexpected denial on prefix rule")
+	}
+	if a.Authorize("frontend", URI("com.myapp.add"), ActionRegister) {
+		t.Error("Unexpected authorization on action")
+	}
+	if !a.Authorize("frontend", URI("com.foo.update"), ActionSubscribe) {
+		t.Error("Unexpected denial on wildcard rule")
+	}
+	if a.Authorize("frontend", URI("com.foo.bar.update"), ActionSubscribe) {
+		t.Error("Unexpected authorization on wildcard rule")
+	}
+}
+
+func TestRuleAuthorizerHotReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wampire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rules.json")
+	rules := `[{"role": "frontend", "uri": "com.myapp.", "match": "prefix", "action": "call"}]`
+	if err := ioutil.WriteFile(path, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := LoadRuleAuthorizer(path)
+	if err != nil {
+		t.Fatal("Unexpected error loading rules ", err)
+	}
+	go a.Watch(time.Millisecond * 10)
+	defer a.Terminate()
+
+	if !a.Authorize("frontend", URI("com.myapp.foo"), ActionCall) {
+		t.Error("Unexpected denial on loaded rules")
+	}
+
+	rules = `[{"role": "frontend", "uri": "com.myapp.", "match": "prefix", "action": "call", "deny": true}]`
+	if err := ioutil.WriteFile(path, []byte(rules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// force modification time change on coarse grained file systems
+	os.Chtimes(path, time.Now().Add(time.Second), time.Now().Add(time.Second))
+	time.Sleep(time.Millisecond * 100)
+
+	if a.Authorize("frontend", URI("com.myapp.foo"), ActionCall) {
+		t.Error("Unexpected authorization after rules reload")
+	}
+}
+
+func TestDealerCallNotAuthorized(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	s := NewSession(NewFakePeer(PeerID("123")))
+	s.identity = &Identity{AuthRole: ANONYMOUS}
+
+	d.Call(&Call{Request: ID(1234), Procedure: URI("wampire.core.dealer.dump")}, s)
+	r := <-s.Receive()
+	e, ok := r.(*Error)
+	if !ok {
+		t.Fatal("Unexpected call response ", r.MsgType())
+	}
+	if e.Type != CALL || e.Request != ID(1234) || e.Error != URI("wamp.error.not_authorized") {
+		t.Error("Unexpected not authorized error ", e)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 )
 
 type Broker interface {
@@ -15,55 +16,106 @@ type Broker interface {
 
 type defaultBroker struct {
 	topics        map[Topic]map[ID]bool   //maps topics to subscriptions
-	subscriptions map[ID]*Session         //a peer may have many subscriptions
+	subscriptions map[ID]*subscription    //a peer may have many subscriptions
 	topicPeers    map[Topic]map[PeerID]ID //maps peers by topic on subscription
+	patterns      map[ID]uriPattern
+	patternPeers  map[uriPattern]map[PeerID]ID //maps peers by pattern on subscription
+	prefixes      *uriTrie                     //prefix subscriptions index
+	wildcards     *uriTrie                     //wildcard subscriptions index
+	ids           *idGenerator                 //router scope subscription ids
 	mutex         *sync.RWMutex
 	metaEvents    SessionMetaEventHandler
+	authorizer    Authorizer
+	disclosure    string        // publisher disclosure policy
+	history       *eventHistory // retained events, nil disables event history
 }
 
-func NewBroker(smeh SessionMetaEventHandler) *defaultBroker {
+func NewBroker(smeh SessionMetaEventHandler, a Authorizer) *defaultBroker {
 	b := &defaultBroker{
 		topics:        make(map[Topic]map[ID]bool),
-		subscriptions: make(map[ID]*Session),
+		subscriptions: make(map[ID]*subscription),
 		topicPeers:    make(map[Topic]map[PeerID]ID),
+		patterns:      make(map[ID]uriPattern),
+		patternPeers:  make(map[uriPattern]map[PeerID]ID),
+		prefixes:      newURITrie(),
+		wildcards:     newURITrie(),
+		ids:           newIdGenerator(),
 		mutex:         &sync.RWMutex{},
 		metaEvents:    smeh,
+		authorizer:    a,
 	}
 
-	// intialize session meta event topic
-	eventsTopic := Topic("wampire.session.meta.events")
-	b.topics[eventsTopic] = map[ID]bool{}
-	b.topicPeers[eventsTopic] = map[PeerID]ID{}
-	log.Println("Session meta events topic created: wampire.session.meta.events")
-
 	return b
 }
 
+// subscription is shared by sessions subscribed on same topic and match policy
+type subscription struct {
+	id       ID
+	topic    Topic
+	match    string
+	created  time.Time
+	sessions map[PeerID]*Session
+}
+
+// details describes subscription on meta api
+func (sub *subscription) details() map[string]interface{} {
+	return map[string]interface{}{
+		"id":      sub.id,
+		"created": sub.created.UTC().Format(time.RFC3339Nano),
+		"uri":     sub.topic,
+		"match":   sub.match,
+	}
+}
+
 func (b *defaultBroker) Subscribe(msg Message, s *Session) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
 	subscribe, ok := msg.(*Subscribe)
-	log.Println("subscribe invoked ", subscribe.Topic)
 	if !ok {
 		log.Fatal("Unexpected type on subscribe ", msg.MsgType())
 		panic("Unexpected type on subscribe")
 	}
+	log.Println("subscribe invoked ", subscribe.Topic)
 
-	if _, ok = b.topics[subscribe.Topic]; !ok {
-		//create topic!
-		b.topics[subscribe.Topic] = make(map[ID]bool)
-		b.topicPeers[subscribe.Topic] = make(map[PeerID]ID)
+	match, _ := subscribe.Options["match"].(string)
+	if match != "" && !validMatchPolicy(match) {
+		log.Println("Invalid subscription match policy ", match)
+		s.Send(&Error{
+			Type:    SUBSCRIBE,
+			Request: subscribe.Request,
+			Details: map[string]interface{}{"match": match},
+			Error:   URI("wamp.error.invalid_argument"),
+		})
+		return
+	}
 
-		b.metaEvents.Fire(
-			s.ID(),
-			URI("wampire.subscription.on_create"),
-			map[string]interface{}{},
-		)
+	if !authorized(b.authorizer, s, URI(subscribe.Topic), ActionSubscribe) {
+		log.Println("Subscribe not authorized ", subscribe.Topic, s.ID())
+		s.Send(notAuthorized(SUBSCRIBE, subscribe.Request, URI(subscribe.Topic)))
+		return
+	}
+
+	if match == "" {
+		match = EXACT
+	}
+	var peers map[PeerID]ID
+	if match == EXACT {
+		if _, ok = b.topics[subscribe.Topic]; !ok {
+			b.topics[subscribe.Topic] = make(map[ID]bool)
+			b.topicPeers[subscribe.Topic] = make(map[PeerID]ID)
+		}
+		peers = b.topicPeers[subscribe.Topic]
+	} else {
+		pattern := uriPattern{match: match, uri: URI(subscribe.Topic)}
+		if _, ok = b.patternPeers[pattern]; !ok {
+			b.patternPeers[pattern] = make(map[PeerID]ID)
+		}
+		peers = b.patternPeers[pattern]
 	}
 
 	//check if subscriptor is already register to topic
-	if subs, ok := b.topicPeers[subscribe.Topic][s.ID()]; ok {
+	if subs, ok := peers[s.ID()]; ok {
 		log.Println("Session already subscribed on subscription ", subs)
 		response := &Error{
 			Error: URI("Peer already subscribed on subscription"),
@@ -72,24 +124,58 @@ func (b *defaultBroker) Subscribe(msg Message, s *Session) {
 		return
 	}
 
-	subscriptionId := NewId()
-	b.topicPeers[subscribe.Topic][s.ID()] = subscriptionId
-	b.topics[subscribe.Topic][subscriptionId] = true
-	b.subscriptions[subscriptionId] = s
+	sub, ok := b.lookup(match, subscribe.Topic)
+	if !ok {
+		sub = &subscription{
+			id:       b.ids.next(),
+			topic:    subscribe.Topic,
+			match:    match,
+			created:  time.Now(),
+			sessions: make(map[PeerID]*Session),
+		}
+		b.subscriptions[sub.id] = sub
+		switch match {
+		case PREFIX:
+			b.patterns[sub.id] = uriPattern{match: match, uri: URI(subscribe.Topic)}
+			b.prefixes.add(prefixKeys(string(subscribe.Topic)), sub.id)
+		case WILDCARD:
+			b.patterns[sub.id] = uriPattern{match: match, uri: URI(subscribe.Topic)}
+			b.wildcards.add(wildcardKeys(string(subscribe.Topic)), sub.id)
+		default:
+			b.topics[subscribe.Topic][sub.id] = true
+		}
+
+		b.fireMetaEvent(s, Topic("wamp.subscription.on_create"), sub, sub.details())
+	}
+	subscriptionId := sub.id
+	peers[s.ID()] = subscriptionId
+	sub.sessions[s.ID()] = s
 
 	// Add subscription to session
 	s.addSubscription(subscriptionId, subscribe.Topic)
-	b.metaEvents.Fire(
-		s.ID(),
-		URI("wampire.subscription.on_subscribe"),
-		map[string]interface{}{},
-	)
+	b.fireMetaEvent(s, Topic("wamp.subscription.on_subscribe"), sub, sub.id)
 
 	response := &Subscribed{
 		Request:      subscribe.Request,
 		Subscription: subscriptionId,
 	}
 	s.Send(response)
+
+	// replay retained events after subscribed
+	if getRetained, _ := subscribe.Options["get_retained"].(bool); getRetained && b.history != nil {
+		for _, e := range b.history.retained(match, subscribe.Topic) {
+			if !authorized(b.authorizer, s, URI(e.Topic), ActionSubscribe) {
+				continue
+			}
+			s.Send(&Event{
+				Subscription: subscriptionId,
+				Publication:  e.Publication,
+				Details:      map[string]interface{}{"topic": e.Topic, "retained": true},
+				Arguments:    e.Arguments,
+				ArgumentsKw:  e.ArgumentsKw,
+			})
+		}
+	}
 }
 
 func (b *defaultBroker) UnSubscribe(msg Message, s *Session) {
@@ -102,8 +188,7 @@ func (b *defaultBroker) UnSubscribe(msg Message, s *Session) {
 		panic("Unexpected type on UnSubscribe")
 	}
 
-	topic, ok := s.subscriptions[unsubscribe.Subscription]
-	if !ok {
+	if _, ok := s.getSubscriptions()[unsubscribe.Subscription]; !ok {
 		uri := "topic not found to this Subscription"
 		log.Println(uri, unsubscribe)
 
@@ -115,7 +200,7 @@ func (b *defaultBroker) UnSubscribe(msg Message, s *Session) {
 		return
 	}
 
-	session, ok := b.subscriptions[unsubscribe.Subscription]
+	sub, ok := b.subscriptions[unsubscribe.Subscription]
 	if !ok {
 		uri := "peer not found to this Subscription"
 		log.Println(uri, unsubscribe)
@@ -130,31 +215,7 @@ func (b *defaultBroker) UnSubscribe(msg Message, s *Session) {
 
 	//Remove session subscription
 	s.removeSubscription(unsubscribe.Subscription)
-	//remove peer from subscription map
-	delete(b.subscriptions, unsubscribe.Subscription)
-	//remove peer from topic map
-	delete(b.topicPeers[topic], session.ID())
-	//remove subscription from topic
-	delete(b.topics[topic], unsubscribe.Subscription)
-
-	//if void topic remove it
-	if len(b.topics[topic]) == 0 && topic != Topic("wampire.session.meta.events") {
-		delete(b.topics, topic)
-	}
-	//if void topic remove it
-	if len(b.topicPeers[topic]) == 0 && topic != Topic("wampire.session.meta.events") {
-		delete(b.topicPeers, topic)
-		b.metaEvents.Fire(
-			session.ID(),
-			URI("wampire.subscription.on_delete"),
-			map[string]interface{}{},
-		)
-	}
-	b.metaEvents.Fire(
-		session.ID(),
-		URI("wampire.subscription.on_unsubscribe"),
-		map[string]interface{}{},
-	)
+	b.unSubscribe(sub, s)
 
 	response := &Unsubscribed{
 		Request: unsubscribe.Request,
@@ -163,6 +224,76 @@ func (b *defaultBroker) UnSubscribe(msg Message, s *Session) {
 	s.Send(response)
 }
 
+// unSubscribe removes session from subscription, void subscriptions are deleted
+func (b *defaultBroker) unSubscribe(sub *subscription, s *Session) {
+	delete(sub.sessions, s.ID())
+	if pattern, ok := b.patterns[sub.id]; ok {
+		delete(b.patternPeers[pattern], s.ID())
+	} else {
+		//remove peer from topic map
+		delete(b.topicPeers[sub.topic], s.ID())
+	}
+	b.fireMetaEvent(s, Topic("wamp.subscription.on_unsubscribe"), sub, sub.id)
+
+	if len(sub.sessions) > 0 {
+		return
+	}
+
+	delete(b.subscriptions, sub.id)
+	if pattern, ok := b.patterns[sub.id]; ok {
+		b.unSubscribePattern(pattern, sub.id)
+	} else {
+		//remove subscription from topic
+		delete(b.topics[sub.topic], sub.id)
+
+		//if void topic remove it
+		if len(b.topics[sub.topic]) == 0 {
+			delete(b.topics, sub.topic)
+		}
+		//if void topic remove it
+		if len(b.topicPeers[sub.topic]) == 0 {
+			delete(b.topicPeers, sub.topic)
+		}
+	}
+	b.fireMetaEvent(s, Topic("wamp.subscription.on_delete"), sub, sub.id)
+}
+
+// fireMetaEvent publishes subscription meta event with (session, subscription)
+// arguments, subscription id and topic keyword arguments
+func (b *defaultBroker) fireMetaEvent(s *Session, event Topic, sub *subscription, argument interface{}) {
+	b.metaEvents.Fire(
+		s.ID(),
+		event,
+		[]interface{}{s.sessionID, argument},
+		map[string]interface{}{"subscription": sub.id, "topic": sub.topic, "match": sub.match},
+	)
+}
+
+func (b *defaultBroker) unSubscribePattern(pattern uriPattern, id ID) {
+	delete(b.patterns, id)
+	delete(b.patternPeers, pattern)
+	if pattern.match == PREFIX {
+		b.prefixes.remove(prefixKeys(string(pattern.uri)), id)
+	} else {
+		b.wildcards.remove(wildcardKeys(string(pattern.uri)), id)
+	}
+}
+
+// lookup returns subscription on topic with match policy
+func (b *defaultBroker) lookup(match string, topic Topic) (*subscription, bool) {
+	if match == EXACT {
+		for id := range b.topics[topic] {
+			return b.subscriptions[id], true
+		}
+		return nil, false
+	}
+	for _, id := range b.patternPeers[uriPattern{match: match, uri: URI(topic)}] {
+		return b.subscriptions[id], true
+	}
+
+	return nil, false
+}
+
 func (b *defaultBroker) Publish(msg Message, s *Session) {
 	b.mutex.RLock()
 	defer b.mutex.RUnlock()
@@ -172,36 +303,65 @@ func (b *defaultBroker) Publish(msg Message, s *Session) {
 		log.Fatal("Unexpected type on publish ", msg.MsgType())
 	}
 
-	subscribers, ok := b.topics[publish.Topic]
-	if !ok {
-		uri := "Topic not found"
-		log.Println(uri, publish)
+	// publisher only hears back on acknowledged publications
+	acknowledge, _ := publish.Options["acknowledge"].(bool)
+	if !authorized(b.authorizer, s, URI(publish.Topic), ActionPublish) {
+		log.Println("Publish not authorized ", publish.Topic, s.ID())
+		if acknowledge {
+			s.Send(notAuthorized(PUBLISH, publish.Request, URI(publish.Topic)))
+		}
+		return
+	}
 
-		response := &Error{
-			Request: publish.Request,
-			Error:   URI(uri),
+	disclose, err := discloseIdentity(b.disclosure, publish.Options)
+	if err != nil {
+		log.Println("Publish disclosure not allowed ", publish.Topic, s.ID())
+		if acknowledge {
+			s.Send(disclosureDisallowed(PUBLISH, publish.Request, err))
 		}
-		s.Send(response)
 		return
 	}
-	//iterate on topic subscribers
+
+	subscribers := b.matchSubscriptions(publish.Topic)
+
+	filter := newPublishFilter(publish.Options)
+	// publish options are publisher private, never forwarded to subscribers
+	details := map[string]interface{}{"topic": publish.Topic}
+	if disclose {
+		for k, v := range s.disclose("publisher") {
+			details[k] = v
+		}
+	}
+	publicationId := NewId()
+	if b.history != nil {
+		b.history.store(&StoredEvent{
+			Publication: publicationId,
+			Timestamp:   time.Now(),
+			Topic:       publish.Topic,
+			Arguments:   publish.Arguments,
+			ArgumentsKw: publish.ArgumentsKw,
+		})
+	}
+	//iterate on topic subscriptions
 	for subscriptionId, _ := range subscribers {
-		//find peer from subscriber
-		session, ok := b.subscriptions[subscriptionId]
+		sub, ok := b.subscriptions[subscriptionId]
 		if !ok {
-			log.Println("Peer not found")
+			log.Println("Subscription not found ", subscriptionId)
 			continue
 		}
 
-		if session.ID() != s.ID() {
-			if publish.Options == nil {
-				publish.Options = map[string]interface{}{}
+		for _, session := range sub.sessions {
+			if !filter.allows(session, s) {
+				continue
+			}
+			// pattern subscriptions may match topics denied to subscriber
+			if sub.match != EXACT && !authorized(b.authorizer, session, URI(publish.Topic), ActionSubscribe) {
+				continue
 			}
-			publish.Options["topic"] = publish.Topic
 			event := &Event{
 				Subscription: subscriptionId,
-				Publication:  publish.Request,
-				Details:      publish.Options,
+				Publication:  publicationId,
+				Details:      details,
 				Arguments:    publish.Arguments,
 				ArgumentsKw:  publish.ArgumentsKw,
 			}
@@ -210,28 +370,292 @@ func (b *defaultBroker) Publish(msg Message, s *Session) {
 		}
 	}
 
+	if !acknowledge {
+		return
+	}
 	response := &Published{
-		Request: publish.Request,
+		Request:     publish.Request,
+		Publication: publicationId,
 	}
 	s.Send(response)
 }
 
+// matchSubscriptions collects exact, prefix and wildcard subscriptions on topic
+func (b *defaultBroker) matchSubscriptions(topic Topic) map[ID]bool {
+	exact := b.topics[topic]
+	if len(b.patterns) == 0 {
+		return exact
+	}
+
+	subscribers := map[ID]bool{}
+	for id := range exact {
+		subscribers[id] = true
+	}
+	b.prefixes.matchPrefix(prefixKeys(string(topic)), subscribers)
+	b.wildcards.matchWildcard(wildcardKeys(string(topic)), subscribers)
+
+	return subscribers
+}
+
 func (b *defaultBroker) Handlers() map[URI]Handler {
 	return map[URI]Handler{
 		"wampire.subscription.list_subscribers":       b.listSubscribers,
 		"wampire.subscription.list_topics":            b.listTopics,
 		"wampire.subscription.count_subscribers":      b.countSubscribers,
 		"wampire.subscription.list_topic_subscribers": b.listTopicSubscriptions,
+		"wamp.subscription.get_events":                b.getEvents,
+		"wampire.subscription.revoke":                 b.revokeSubscription,
+		"wamp.subscription.list":                      b.metaList,
+		"wamp.subscription.lookup":                    b.metaLookup,
+		"wamp.subscription.match":                     b.metaMatch,
+		"wamp.subscription.get":                       b.metaGet,
+		"wamp.subscription.list_subscribers":          b.metaListSubscribers,
+		"wamp.subscription.count_subscribers":         b.metaCountSubscribers,
 	}
 }
 
+// revokeSubscription removes subscription sessions, or session id second
+// argument only, sending router unsubscribed with reason keyword argument,
+// returns revoked session ids
+func (b *defaultBroker) revokeSubscription(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	b.mutex.Lock()
+	sub, err := b.subscriptionArgument(inv)
+	if err != nil {
+		b.mutex.Unlock()
+		return nil, err
+	}
+	session, err := sessionArgument(inv)
+	if err != nil {
+		b.mutex.Unlock()
+		return nil, err
+	}
+	reason := revokeReason(inv, URI("wampire.subscription.revoked"))
+
+	sessions := []*Session{}
+	for _, s := range sub.sessions {
+		if session != 0 && s.sessionID != session {
+			continue
+		}
+		log.Println("Revoking subscription ", sub.id, " on session ", s.ID(), reason)
+		s.removeSubscription(sub.id)
+		b.unSubscribe(sub, s)
+		sessions = append(sessions, s)
+	}
+	b.mutex.Unlock()
+	if session != 0 && len(sessions) == 0 {
+		return nil, newHandlerError(URI("wamp.error.no_such_session"))
+	}
+
+	// notify revoked sessions out of broker lock
+	revoked := []interface{}{}
+	for _, s := range sessions {
+		s.Send(&Unsubscribed{
+			Details: map[string]interface{}{"subscription": sub.id, "reason": reason},
+		})
+		revoked = append(revoked, s.sessionID)
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{revoked},
+	}, nil
+}
+
+// metaList returns subscription ids by match policy
+func (b *defaultBroker) metaList(msg Message) (Message, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	list := map[string]interface{}{}
+	ids := map[string][]interface{}{EXACT: {}, PREFIX: {}, WILDCARD: {}}
+	for id, sub := range b.subscriptions {
+		ids[sub.match] = append(ids[sub.match], id)
+	}
+	for match, l := range ids {
+		list[match] = l
+	}
+
+	return &Yield{
+		Request:   msg.(*Invocation).Request,
+		Arguments: []interface{}{list},
+	}, nil
+}
+
+// metaLookup returns subscription id on topic with match option, nil if not found
+func (b *defaultBroker) metaLookup(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	topic, err := topicArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+	match := EXACT
+	if len(inv.Arguments) > 1 {
+		if options, ok := inv.Arguments[1].(map[string]interface{}); ok {
+			if m, ok := options["match"].(string); ok && m != "" {
+				match = m
+			}
+		}
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	var id interface{}
+	if sub, ok := b.lookup(match, topic); ok {
+		id = sub.id
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{id},
+	}, nil
+}
+
+// metaMatch returns subscription ids matching topic, nil if none
+func (b *defaultBroker) metaMatch(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	topic, err := topicArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	var ids interface{}
+	if subscribers := b.matchSubscriptions(topic); len(subscribers) > 0 {
+		list := []interface{}{}
+		for id := range subscribers {
+			list = append(list, id)
+		}
+		ids = list
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{ids},
+	}, nil
+}
+
+// metaGet returns subscription details
+func (b *defaultBroker) metaGet(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	sub, err := b.subscriptionArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{sub.details()},
+	}, nil
+}
+
+// metaListSubscribers returns subscribed session ids
+func (b *defaultBroker) metaListSubscribers(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	sub, err := b.subscriptionArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []interface{}{}
+	for _, s := range sub.sessions {
+		sessions = append(sessions, s.sessionID)
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{sessions},
+	}, nil
+}
+
+// metaCountSubscribers returns subscribed sessions count
+func (b *defaultBroker) metaCountSubscribers(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	sub, err := b.subscriptionArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{len(sub.sessions)},
+	}, nil
+}
+
+// subscriptionArgument returns subscription on first invocation argument,
+// mutex must be held
+func (b *defaultBroker) subscriptionArgument(inv *Invocation) (*subscription, error) {
+	if len(inv.Arguments) < 1 {
+		return nil, newHandlerError(URI("wamp.error.invalid_argument"), "Void subscription argument")
+	}
+	id, ok := toInt(inv.Arguments[0])
+	if !ok {
+		return nil, newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid subscription argument %v", inv.Arguments[0]))
+	}
+	sub, ok := b.subscriptions[ID(id)]
+	if !ok {
+		return nil, newHandlerError(URI("wamp.error.no_such_subscription"))
+	}
+
+	return sub, nil
+}
+
+// sessionArgument returns optional session id second argument, void if not set
+func sessionArgument(inv *Invocation) (ID, error) {
+	if len(inv.Arguments) < 2 || inv.Arguments[1] == nil {
+		return 0, nil
+	}
+	id, ok := toInt(inv.Arguments[1])
+	if !ok {
+		return 0, newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid session argument %v", inv.Arguments[1]))
+	}
+
+	return ID(id), nil
+}
+
+// revokeReason returns reason keyword argument or default reason
+func revokeReason(inv *Invocation, reason URI) URI {
+	if r, ok := inv.ArgumentsKw["reason"].(string); ok && r != "" {
+		return URI(r)
+	}
+
+	return reason
+}
+
+func topicArgument(inv *Invocation) (Topic, error) {
+	if len(inv.Arguments) < 1 {
+		return "", newHandlerError(URI("wamp.error.invalid_argument"), "Void topic argument")
+	}
+	topic, ok := inv.Arguments[0].(string)
+	if !ok {
+		return "", newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid topic argument %v", inv.Arguments[0]))
+	}
+
+	return Topic(topic), nil
+}
+
 func (b *defaultBroker) listSubscribers(msg Message) (Message, error) {
 	b.mutex.RLock()
 	defer b.mutex.RUnlock()
 
 	subs := map[string]interface{}{}
-	for id, s := range b.subscriptions {
-		subs[fmt.Sprintf("%d", id)] = s.ID()
+	for id, sub := range b.subscriptions {
+		peers := []interface{}{}
+		for peer := range sub.sessions {
+			peers = append(peers, peer)
+		}
+		subs[fmt.Sprintf("%d", id)] = peers
 	}
 	inv := msg.(*Invocation)
 
@@ -266,10 +690,14 @@ func (b *defaultBroker) countSubscribers(msg Message) (Message, error) {
 	defer b.mutex.RUnlock()
 
 	inv := msg.(*Invocation)
+	count := 0
+	for _, sub := range b.subscriptions {
+		count += len(sub.sessions)
+	}
 
 	return &Yield{
 		Request:   inv.Request,
-		Arguments: []interface{}{len(b.subscriptions)},
+		Arguments: []interface{}{count},
 	}, nil
 }
 
@@ -295,15 +723,17 @@ func (b *defaultBroker) listTopicSubscriptions(msg Message) (Message, error) {
 	list := []interface{}{}
 	for id, _ := range subscribers {
 		b.mutex.RLock()
-		s, ok := b.subscriptions[id]
+		sub, ok := b.subscriptions[id]
+		if ok {
+			for peer := range sub.sessions {
+				list = append(list, peer)
+			}
+		}
 		b.mutex.RUnlock()
 		if !ok {
 			uri := fmt.Sprintf("Topic %s Subscriptior %d not found", topic, id)
 			log.Println(uri, uri)
-
-			continue
 		}
-		list = append(list, s.ID())
 	}
 
 	return &Yield{
@@ -311,3 +741,80 @@ func (b *defaultBroker) listTopicSubscriptions(msg Message) (Message, error) {
 		Arguments: list,
 	}, nil
 }
+
+// getEvents returns retained events on subscription, most recent first,
+// optional limit argument, before and after publication ids or since and
+// until RFC3339 timestamps keyword arguments page through events
+func (b *defaultBroker) getEvents(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	b.mutex.RLock()
+	sub, err := b.subscriptionArgument(inv)
+	b.mutex.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	q, err := eventQuery(inv.ArgumentsKw)
+	if err != nil {
+		return nil, err
+	}
+	q.Match, q.Topic = sub.match, sub.topic
+	if len(inv.Arguments) > 1 {
+		q.Limit, _ = toInt(inv.Arguments[1])
+	}
+
+	events := []interface{}{}
+	if b.history != nil {
+		history, err := b.history.history(q)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range history {
+			events = append(events, map[string]interface{}{
+				"publication": e.Publication,
+				"timestamp":   e.Timestamp.UTC().Format(time.RFC3339Nano),
+				"topic":       e.Topic,
+				"args":        e.Arguments,
+				"kwargs":      e.ArgumentsKw,
+			})
+		}
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{events},
+	}, nil
+}
+
+// eventQuery parses get events paging keyword arguments
+func eventQuery(kwargs map[string]interface{}) (*EventQuery, error) {
+	q := &EventQuery{}
+	for k, v := range kwargs {
+		switch k {
+		case "before", "after":
+			id, ok := toInt(v)
+			if !ok {
+				return nil, newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid %s publication %v", k, v))
+			}
+			if k == "before" {
+				q.Before = ID(id)
+			} else {
+				q.After = ID(id)
+			}
+		case "since", "until":
+			s, _ := v.(string)
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid %s timestamp %v", k, v))
+			}
+			if k == "since" {
+				q.Since = t
+			} else {
+				q.Until = t
+			}
+		case "limit":
+			q.Limit, _ = toInt(v)
+		}
+	}
+
+	return q, nil
+}
@@ -1,13 +1,16 @@
 package core
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestBrokerPublish(t *testing.T) {
 	// Mocked MetaEventsChannel
 	m := &fakeSessionMetaEventsHandler{}
-	b := NewBroker(m)
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
 
 	sessionA := NewSession(NewFakePeer(PeerID("PeerA")))
 	sessionB := NewSession(NewFakePeer(PeerID("PeerB")))
@@ -38,7 +41,7 @@ func TestBrokerPublish(t *testing.T) {
 
 func TestBrokerSubscribe(t *testing.T) {
 	m := &fakeSessionMetaEventsHandler{}
-	b := NewBroker(m)
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
 	s := NewSession(NewFakePeer(PeerID("123")))
 
 	subs := &Subscribe{Request: ID(123), Topic: Topic("foo")}
@@ -53,14 +56,10 @@ func TestBrokerSubscribe(t *testing.T) {
 		t.Error("Unexpected response ID")
 	}
 
-	if len(b.topicPeers) != 2 {
+	if len(b.topicPeers) != 1 {
 		t.Error("Unexpected topicPeers size")
 	}
 
-	if len(b.topicPeers[Topic("wampire.session.meta.events")]) != 0 {
-		t.Error("Unexpected topicPeers on peers size")
-	}
-
 	if len(b.topicPeers[Topic("foo")]) != 1 {
 		t.Error("Unexpected topicPeers on peers size")
 	}
@@ -83,7 +82,7 @@ func TestBrokerSubscribe(t *testing.T) {
 
 func TestBrokerUnSubscribe(t *testing.T) {
 	m := &fakeSessionMetaEventsHandler{}
-	b := NewBroker(m)
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
 	s := NewSession(NewFakePeer(PeerID("123")))
 
 	subs := &Subscribe{Request: ID(123), Topic: Topic("foo")}
@@ -106,12 +105,7 @@ func TestBrokerUnSubscribe(t *testing.T) {
 		t.Error("Unexpected response ID")
 	}
 
-	if len(b.topicPeers[Topic("wampire.session.meta.events")]) != 0 {
-		t.Error("Unexpected topicPeers on peers size")
-	}
-
-	// topic wampire.session.meta.events still exists
-	if len(b.topicPeers) != 1 {
+	if len(b.topicPeers) != 0 {
 		t.Error("Unexpected topicPeers size")
 	}
 
@@ -125,3 +119,421 @@ func TestBrokerUnSubscribe(t *testing.T) {
 		t.Error("Session topic subscription not found")
 	}
 }
+
+func TestBrokerPatternSubscriptionAuthorization(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	b := NewBroker(m, NewRuleAuthorizer([]*Rule{
+		{Role: "guest", URI: URI("com.myapp.secret."), Match: PREFIX, Action: ActionSubscribe, Deny: true},
+		{Role: ANY, URI: URI(""), Match: PREFIX, Action: Action(ANY)},
+	}))
+	publisher := NewSession(NewFakePeer(PeerID("publisher")))
+
+	subscribers := []*Session{}
+	for i, topic := range []Topic{Topic(""), Topic("com.myapp.sec"), Topic("com..secret.")} {
+		s := NewSession(NewFakePeer(PeerID(fmt.Sprintf("guest%d", i))))
+		s.identity = &Identity{AuthID: "guest", AuthRole: "guest"}
+		match := PREFIX
+		if i == 2 {
+			match = WILDCARD
+		}
+		b.Subscribe(&Subscribe{Request: ID(i + 1), Topic: topic, Options: map[string]interface{}{"match": match}}, s)
+		if r := <-s.Receive(); r.MsgType() != SUBSCRIBED {
+			t.Fatal("Error subscribing ", r.MsgType())
+		}
+		subscribers = append(subscribers, s)
+	}
+
+	b.Publish(&Publish{Request: ID(10), Topic: Topic("com.myapp.secret.key")}, publisher)
+	b.Publish(&Publish{Request: ID(11), Topic: Topic("com.myapp.sec")}, publisher)
+	for i, s := range subscribers {
+		events := 0
+		timeout := time.After(time.Millisecond * 100)
+	receive:
+		for {
+			select {
+			case r := <-s.Receive():
+				if e, ok := r.(*Event); !ok || e.Details["topic"] != Topic("com.myapp.sec") {
+					t.Error("Unexpected event on denied topic ", i, r)
+				}
+				events++
+			case <-timeout:
+				break receive
+			}
+		}
+		// wildcard subscription only matches denied topic
+		expected := 1
+		if i == 2 {
+			expected = 0
+		}
+		if events != expected {
+			t.Error("Unexpected events on subscriber ", i, events)
+		}
+	}
+}
+
+func TestBrokerPatternSubscriptions(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
+	publisher := NewSession(NewFakePeer(PeerID("publisher")))
+
+	subscriptions := map[ID]*Session{}
+	patterns := []*Subscribe{
+		{Request: ID(1), Topic: Topic("com.myapp.user.created"), Options: map[string]interface{}{}},
+		{Request: ID(2), Topic: Topic("com.myapp."), Options: map[string]interface{}{"match": PREFIX}},
+		{Request: ID(3), Topic: Topic("com..user."), Options: map[string]interface{}{"match": WILDCARD}},
+		{Request: ID(4), Topic: Topic("com.other."), Options: map[string]interface{}{"match": PREFIX}},
+		{Request: ID(5), Topic: Topic("com..user"), Options: map[string]interface{}{"match": WILDCARD}},
+	}
+	for _, subs := range patterns {
+		s := NewSession(NewFakePeer(PeerID(fmt.Sprintf("peer%d", subs.Request))))
+		b.Subscribe(subs, s)
+		r := <-s.Receive()
+		if r.MsgType() != SUBSCRIBED {
+			t.Fatal("Error subscribing ", r.MsgType())
+		}
+		subscriptions[r.(*Subscribed).Subscription] = s
+	}
+
+	b.Publish(&Publish{Request: ID(9999), Topic: Topic("com.myapp.user.created"), Options: map[string]interface{}{"acknowledge": true}}, publisher)
+	r := <-publisher.Receive()
+	if r.MsgType() != PUBLISHED {
+		t.Fatal("Unexpected publish response ", r.MsgType())
+	}
+
+	received := map[PeerID]bool{}
+	for id, s := range subscriptions {
+		select {
+		case r := <-s.Receive():
+			e, ok := r.(*Event)
+			if !ok {
+				t.Fatal("Unexpected message type ", r.MsgType())
+			}
+			if e.Subscription != id {
+				t.Error("Unexpected event subscription ", e.Subscription)
+			}
+			if e.Details["topic"] != Topic("com.myapp.user.created") {
+				t.Error("Unexpected event topic ", e.Details["topic"])
+			}
+			received[s.ID()] = true
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
+	if len(received) != 3 || !received[PeerID("peer1")] || !received[PeerID("peer2")] || !received[PeerID("peer3")] {
+		t.Error("Unexpected event receivers ", received)
+	}
+
+	// void patterns are removed from indexes on unsubscribe
+	for id, s := range subscriptions {
+		b.UnSubscribe(&Unsubscribe{Request: NewId(), Subscription: id}, s)
+		if r := <-s.Receive(); r.MsgType() != UNSUBSCRIBED {
+			t.Error("Error unsubscribing ", r.MsgType())
+		}
+	}
+	if len(b.patterns) != 0 || len(b.patternPeers) != 0 || len(b.prefixes.children) != 0 || len(b.wildcards.children) != 0 {
+		t.Error("Unexpected pattern subscriptions after unsubscribe")
+	}
+}
+
+func TestBrokerSubscribeInvalidMatchPolicy(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
+	s := NewSession(NewFakePeer(PeerID("123")))
+
+	b.Subscribe(&Subscribe{Request: ID(1), Topic: Topic("foo"), Options: map[string]interface{}{"match": "regex"}}, s)
+	r := <-s.Receive()
+	if r.MsgType() != ERROR || r.(*Error).Error != URI("wamp.error.invalid_argument") {
+		t.Error("Expected invalid argument error ", r)
+	}
+}
+
+func TestBrokerPublisherDisclosure(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
+	publisher := NewSession(NewFakePeer(PeerID("publisher")))
+	publisher.sessionID = ID(42)
+	publisher.identity = &Identity{AuthID: "joe", AuthRole: "frontend"}
+	subscriber := NewSession(NewFakePeer(PeerID("subscriber")))
+
+	b.Subscribe(&Subscribe{Request: ID(1), Topic: Topic("foo")}, subscriber)
+	<-subscriber.Receive()
+
+	b.Publish(&Publish{Request: ID(2), Topic: Topic("foo"), Options: map[string]interface{}{"disclose_me": true}}, publisher)
+	e := (<-subscriber.Receive()).(*Event)
+	if e.Details["publisher"] != ID(42) || e.Details["publisher_authid"] != "joe" || e.Details["publisher_authrole"] != "frontend" {
+		t.Error("Unexpected publisher disclosure ", e.Details)
+	}
+
+	b.Publish(&Publish{Request: ID(3), Topic: Topic("foo")}, publisher)
+	e = (<-subscriber.Receive()).(*Event)
+	if _, ok := e.Details["publisher"]; ok {
+		t.Error("Unexpected publisher disclosure without disclose_me ", e.Details)
+	}
+
+	// realm policy forbids disclosure
+	b.disclosure = DISCLOSE_NEVER
+	b.Publish(&Publish{Request: ID(4), Topic: Topic("foo"), Options: map[string]interface{}{"disclose_me": true, "acknowledge": true}}, publisher)
+	r := <-publisher.Receive()
+	if r.MsgType() != ERROR || r.(*Error).Error != URI("wamp.error.option_disallowed.disclose_me") {
+		t.Error("Expected disclose_me disallowed error ", r)
+	}
+}
+
+func TestBrokerPublishExcludeMe(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
+	s := NewSession(NewFakePeer(PeerID("123")))
+
+	b.Subscribe(&Subscribe{Request: ID(1), Topic: Topic("chat")}, s)
+	<-s.Receive()
+
+	b.Publish(&Publish{Request: ID(2), Topic: Topic("chat"), Options: map[string]interface{}{"exclude_me": false, "acknowledge": true}}, s)
+	received := map[MsgType]Message{}
+	for i := 0; i < 2; i++ {
+		r := <-s.Receive()
+		received[r.MsgType()] = r
+	}
+	e, ok := received[EVENT].(*Event)
+	if !ok {
+		t.Fatal("Expected event echoed to publisher")
+	}
+	if len(e.Details) != 1 || e.Details["topic"] != Topic("chat") {
+		t.Error("Unexpected publish options on event details ", e.Details)
+	}
+}
+
+func TestBrokerPublishAcknowledge(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
+	publisher := NewSession(NewFakePeer(PeerID("publisher")))
+	subscriber := NewSession(NewFakePeer(PeerID("subscriber")))
+
+	// no subscribers is not an error
+	b.Publish(&Publish{Request: ID(1), Topic: Topic("telemetry")}, publisher)
+	b.Publish(&Publish{Request: ID(2), Topic: Topic("telemetry"), Options: map[string]interface{}{"acknowledge": true}}, publisher)
+	r := <-publisher.Receive()
+	if r.MsgType() != PUBLISHED || r.(*Published).Request != ID(2) {
+		t.Fatal("Unexpected publish response ", r)
+	}
+
+	b.Subscribe(&Subscribe{Request: ID(3), Topic: Topic("telemetry")}, subscriber)
+	<-subscriber.Receive()
+	b.Publish(&Publish{Request: ID(4), Topic: Topic("telemetry"), Options: map[string]interface{}{"acknowledge": true}}, publisher)
+	published := (<-publisher.Receive()).(*Published)
+	event := (<-subscriber.Receive()).(*Event)
+	if published.Publication == 0 || published.Publication == published.Request || published.Publication != event.Publication {
+		t.Error("Unexpected publication id ", published, event.Publication)
+	}
+
+	// fire and forget publications get no response
+	b.Publish(&Publish{Request: ID(5), Topic: Topic("telemetry")}, publisher)
+	<-subscriber.Receive()
+	select {
+	case r := <-publisher.Receive():
+		t.Error("Unexpected response on not acknowledged publication ", r.MsgType())
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestBrokerRetainedEvents(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
+	policies := []RetentionPolicy{{URI: URI("dashboard."), Match: PREFIX, Limit: 10}}
+	b.history = newEventHistory(policies, NewMemoryEventStore(policies))
+	publisher := NewSession(NewFakePeer(PeerID("publisher")))
+
+	for i := 0; i < 3; i++ {
+		b.Publish(&Publish{Request: NewId(), Topic: Topic("dashboard.state"), Arguments: []interface{}{i}}, publisher)
+	}
+
+	s := NewSession(NewFakePeer(PeerID("dashboard")))
+	b.Subscribe(&Subscribe{Request: ID(1), Topic: Topic("dashboard.state"), Options: map[string]interface{}{"get_retained": true}}, s)
+	// fake peer sends are not ordered
+	received := map[MsgType]Message{}
+	for i := 0; i < 2; i++ {
+		r := <-s.Receive()
+		received[r.MsgType()] = r
+	}
+	subscribed := received[SUBSCRIBED].(*Subscribed)
+	e, ok := received[EVENT].(*Event)
+	if !ok || e.Details["retained"] != true || e.Arguments[0] != 2 || e.Subscription != subscribed.Subscription {
+		t.Error("Unexpected retained event ", e)
+	}
+
+	yield, err := b.getEvents(&Invocation{Request: ID(2), Arguments: []interface{}{float64(subscribed.Subscription), float64(2)}})
+	if err != nil {
+		t.Fatal("Unexpected error getting events ", err)
+	}
+	events := yield.(*Yield).Arguments[0].([]interface{})
+	if len(events) != 2 || events[0].(map[string]interface{})["args"].([]interface{})[0] != 2 {
+		t.Error("Unexpected subscription events ", events)
+	}
+
+	before := events[1].(map[string]interface{})["publication"]
+	yield, err = b.getEvents(&Invocation{Request: ID(3), Arguments: []interface{}{float64(subscribed.Subscription)}, ArgumentsKw: map[string]interface{}{"before": float64(before.(ID))}})
+	if err != nil {
+		t.Fatal("Unexpected error paging events ", err)
+	}
+	events = yield.(*Yield).Arguments[0].([]interface{})
+	if len(events) != 1 || events[0].(map[string]interface{})["args"].([]interface{})[0] != 0 {
+		t.Error("Unexpected paged subscription events ", events)
+	}
+
+	_, err = b.getEvents(&Invocation{Request: ID(3), Arguments: []interface{}{float64(12345)}})
+	if h, ok := err.(*handlerError); !ok || h.uri != URI("wamp.error.no_such_subscription") {
+		t.Error("Expected no such subscription error ", err)
+	}
+	_, err = b.getEvents(&Invocation{Request: ID(4)})
+	if h, ok := err.(*handlerError); !ok || h.uri != URI("wamp.error.invalid_argument") {
+		t.Error("Expected invalid argument error ", err)
+	}
+	_, err = b.getEvents(&Invocation{Request: ID(5), Arguments: []interface{}{float64(subscribed.Subscription)}, ArgumentsKw: map[string]interface{}{"since": "yesterday"}})
+	if h, ok := err.(*handlerError); !ok || h.uri != URI("wamp.error.invalid_argument") {
+		t.Error("Expected invalid argument error on since timestamp ", err)
+	}
+}
+
+// recordingMetaEventsHandler records meta events arguments by topic
+type recordingMetaEventsHandler struct {
+	fakeSessionMetaEventsHandler
+	mutex    sync.Mutex
+	events   map[Topic][][]interface{}
+	keywords map[Topic][]map[string]interface{}
+}
+
+func (m *recordingMetaEventsHandler) Fire(id PeerID, topic Topic, arguments []interface{}, argumentsKw map[string]interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.events == nil {
+		m.events = map[Topic][][]interface{}{}
+		m.keywords = map[Topic][]map[string]interface{}{}
+	}
+	m.events[topic] = append(m.events[topic], arguments)
+	m.keywords[topic] = append(m.keywords[topic], argumentsKw)
+}
+
+func TestBrokerSubscriptionMetaApi(t *testing.T) {
+	m := &recordingMetaEventsHandler{}
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
+
+	subscribed := map[PeerID]ID{}
+	for i, peer := range []PeerID{"peerA", "peerB"} {
+		s := NewSession(NewFakePeer(peer))
+		s.sessionID = ID(i + 1)
+		b.Subscribe(&Subscribe{Request: ID(1), Topic: Topic("com.myapp.chat"), Options: map[string]interface{}{}}, s)
+		subscribed[peer] = (<-s.Receive()).(*Subscribed).Subscription
+	}
+	s := NewSession(NewFakePeer(PeerID("peerC")))
+	b.Subscribe(&Subscribe{Request: ID(1), Topic: Topic("com.myapp."), Options: map[string]interface{}{"match": PREFIX}}, s)
+	prefixed := (<-s.Receive()).(*Subscribed).Subscription
+
+	id := subscribed[PeerID("peerA")]
+	if subscribed[PeerID("peerB")] != id {
+		t.Fatal("Expected shared subscription on same topic ", subscribed)
+	}
+
+	call := func(h Handler, args ...interface{}) (*Yield, error) {
+		r, err := h(&Invocation{Request: ID(1), Arguments: args})
+		if err != nil {
+			return nil, err
+		}
+		return r.(*Yield), nil
+	}
+
+	y, _ := call(b.metaList)
+	list := y.Arguments[0].(map[string]interface{})
+	if len(list[EXACT].([]interface{})) != 1 || len(list[PREFIX].([]interface{})) != 1 || len(list[WILDCARD].([]interface{})) != 0 {
+		t.Error("Unexpected subscription list ", list)
+	}
+
+	y, _ = call(b.metaLookup, "com.myapp.", map[string]interface{}{"match": PREFIX})
+	if y.Arguments[0] != prefixed {
+		t.Error("Unexpected prefix lookup ", y.Arguments)
+	}
+	y, _ = call(b.metaLookup, "com.myapp.other")
+	if y.Arguments[0] != nil {
+		t.Error("Unexpected lookup on topic without subscription ", y.Arguments)
+	}
+
+	y, _ = call(b.metaMatch, "com.myapp.chat")
+	if len(y.Arguments[0].([]interface{})) != 2 {
+		t.Error("Unexpected matching subscriptions ", y.Arguments)
+	}
+
+	y, _ = call(b.metaGet, float64(id))
+	details := y.Arguments[0].(map[string]interface{})
+	if details["id"] != id || details["uri"] != Topic("com.myapp.chat") || details["match"] != EXACT || details["created"] == "" {
+		t.Error("Unexpected subscription details ", details)
+	}
+
+	y, _ = call(b.metaListSubscribers, float64(id))
+	if len(y.Arguments[0].([]interface{})) != 2 {
+		t.Error("Unexpected subscribers ", y.Arguments)
+	}
+	y, _ = call(b.metaCountSubscribers, float64(id))
+	if y.Arguments[0] != 2 {
+		t.Error("Unexpected subscribers count ", y.Arguments)
+	}
+
+	_, err := call(b.metaGet, float64(12345))
+	if h, ok := err.(*handlerError); !ok || h.uri != URI("wamp.error.no_such_subscription") {
+		t.Error("Expected no such subscription error ", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.events[Topic("wamp.subscription.on_create")]) != 2 || len(m.events[Topic("wamp.subscription.on_subscribe")]) != 3 {
+		t.Error("Unexpected subscription meta events ", m.events)
+	}
+	created := m.events[Topic("wamp.subscription.on_create")][0]
+	if created[0] != ID(1) || created[1].(map[string]interface{})["id"] != id {
+		t.Error("Unexpected on_create payload ", created)
+	}
+	if kw := m.keywords[Topic("wamp.subscription.on_subscribe")][2]; kw["subscription"] != prefixed || kw["topic"] != Topic("com.myapp.") {
+		t.Error("Unexpected on_subscribe keyword arguments ", kw)
+	}
+}
+
+func TestBrokerRevokeSubscription(t *testing.T) {
+	m := &recordingMetaEventsHandler{}
+	b := NewBroker(m, NewRuleAuthorizer(DefaultRules()))
+
+	sessions := []*Session{}
+	var id ID
+	for i := 1; i <= 2; i++ {
+		s := NewSession(NewFakePeer(PeerID(fmt.Sprintf("peer%d", i))))
+		s.sessionID = ID(i)
+		b.Subscribe(&Subscribe{Request: ID(1), Topic: Topic("com.myapp.chat"), Options: map[string]interface{}{}}, s)
+		id = (<-s.Receive()).(*Subscribed).Subscription
+		sessions = append(sessions, s)
+	}
+
+	r, err := b.revokeSubscription(&Invocation{Request: ID(1), Arguments: []interface{}{float64(id), float64(1)}, ArgumentsKw: map[string]interface{}{"reason": "com.myapp.rogue"}})
+	if err != nil || len(r.(*Yield).Arguments[0].([]interface{})) != 1 {
+		t.Fatal("Unexpected revoked sessions ", r, err)
+	}
+	u, ok := (<-sessions[0].Receive()).(*Unsubscribed)
+	if !ok || u.Request != 0 || u.Details["subscription"] != id || u.Details["reason"] != URI("com.myapp.rogue") {
+		t.Error("Unexpected revocation ", u)
+	}
+	if len(sessions[0].getSubscriptions()) != 0 || len(b.subscriptions[id].sessions) != 1 {
+		t.Error("Unexpected subscription state after session revocation")
+	}
+
+	r, err = b.revokeSubscription(&Invocation{Request: ID(2), Arguments: []interface{}{float64(id)}})
+	if err != nil {
+		t.Fatal("Unexpected error revoking subscription ", err)
+	}
+	u = (<-sessions[1].Receive()).(*Unsubscribed)
+	if u.Details["reason"] != URI("wampire.subscription.revoked") {
+		t.Error("Unexpected default revocation reason ", u.Details)
+	}
+	if len(b.subscriptions) != 0 || len(b.topics) != 0 {
+		t.Error("Unexpected subscriptions after revocation")
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.events[Topic("wamp.subscription.on_unsubscribe")]) != 2 || len(m.events[Topic("wamp.subscription.on_delete")]) != 1 {
+		t.Error("Unexpected revocation meta events ", m.events)
+	}
+}
@@ -25,7 +25,7 @@ type cliClient struct {
 	done        chan struct{}
 }
 
-func NewCliClient(host string) *cliClient {
+func NewCliClient(host string, realm core.URI) *cliClient {
 	u := url.URL{Scheme: "ws", Host: host, Path: "/ws"}
 
 	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
@@ -49,13 +49,11 @@ func NewCliClient(host string) *cliClient {
 		core.SUBSCRIBED: c.subscribed,
 		core.RESULT:     c.result,
 		core.INTERRUPT:  c.interrupt,
-		core.YIELD:      c.yield,
 		core.EVENT:      c.event,
 		core.ERROR:      c.error,
 	}
 
 	// @TODO: Handle Hello details
-	realm := core.URI("fooRealm")
 	details := map[string]interface{}{"foo": "bar"}
 	c.sayHello(realm, details)
 	go c.receiveLoop()
@@ -243,6 +241,10 @@ func (p *cliClient) welcome(msg core.Message) error {
 
 func (p *cliClient) result(msg core.Message) error {
 	r := msg.(*core.Result)
+	if progress, ok := r.Details["progress"].(bool); ok && progress {
+		log.Printf("Progress Call Id: %d Update: %v  \n", r.Request, r.ArgumentsKw["update"])
+		return nil
+	}
 	log.Printf("RESULT %d", r.Request)
 	if len(r.ArgumentsKw) > 0 {
 		//format table results
@@ -261,13 +263,6 @@ func (p *cliClient) interrupt(msg core.Message) error {
 	return nil
 }
 
-func (p *cliClient) yield(msg core.Message) error {
-	r := msg.(*core.Yield)
-
-	log.Printf("Yield Call Id: %d Update: %d  \n", r.Request, int(r.ArgumentsKw["update"].(float64)))
-	return nil
-}
-
 func (p *cliClient) event(msg core.Message) error {
 	r := msg.(*core.Event)
 	if len(r.Arguments) > 0 {
@@ -286,7 +281,7 @@ func (p *cliClient) event(msg core.Message) error {
 
 func (p *cliClient) published(msg core.Message) error {
 	r := msg.(*core.Published)
-	log.Printf("published Details: %d \n", r.Request)
+	log.Printf("published Details: %d Publication: %d \n", r.Request, r.Publication)
 	return nil
 }
 
@@ -314,6 +309,7 @@ func main() {
 	//Parse config
 	host := flag.String("Hostname", "localhost", "host name")
 	port := flag.Int("port", 8888, "port")
+	realm := flag.String("realm", "wampire", "realm")
 	flag.Parse()
 
 	c := make(chan os.Signal, 1)
@@ -327,7 +323,7 @@ func main() {
 		syscall.SIGTERM,
 		syscall.SIGQUIT)
 
-	client := NewCliClient(fmt.Sprintf("%s:%d", *host, *port))
+	client := NewCliClient(fmt.Sprintf("%s:%d", *host, *port), core.URI(*realm))
 
 	//serve until signal
 	go func() {
@@ -9,7 +9,7 @@
 
 "use strict";
 
-var demoRealm = "";
+var demoRealm = "wampire";
 var demoPrefix = "io.crossbar.demo";
 
 
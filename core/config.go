@@ -0,0 +1,82 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+const defaultRealm = URI("wampire")
+
+// Config holds server and router startup configuration, rawsocket
+// transport listens on RawSocketPort and RawSocketPath unix socket if set,
+// TLS applies to tcp listeners, CallTimeout is the default call
+// timeout in milliseconds for realms not defining it, void disables it,
+// realm management procedures are only registered on ManagementRealm,
+// void defaults to wampire realm, Authenticators defines router
+// authentication chain, void allows anonymous sessions only
+type Config struct {
+	Port            int                   `json:"port"`
+	RawSocketPort   int                   `json:"rawsocket_port"`
+	RawSocketPath   string                `json:"rawsocket_path"`
+	TLS             *TLSConfig            `json:"tls"`
+	CallTimeout     int                   `json:"call_timeout"`
+	ManagementRealm URI                   `json:"management_realm"`
+	Authenticators  []AuthenticatorConfig `json:"authenticators"`
+	Realms          []RealmConfig         `json:"realms"`
+}
+
+// RealmConfig defines a realm created on router startup,
+// void AuthMethods allows all router authenticators, Authorization
+// is the json rules file path, void uses default rules, CallTimeout
+// in milliseconds applies to calls without timeout option, Disclosure
+// policy on caller and publisher identification is optional, always or never,
+// EventHistory retention policies enable event history on matching topics,
+// stored on EventStoreDir file event store if set, otherwise in memory,
+// file store segments rotate on EventStoreSegmentSize bytes keeping last
+// EventStoreSegments, void values take defaults, EventStore overrides
+// both with a custom event store
+type RealmConfig struct {
+	Name                  URI               `json:"name"`
+	AuthMethods           []string          `json:"authmethods"`
+	Authorization         string            `json:"authorization"`
+	CallTimeout           int               `json:"call_timeout"`
+	Disclosure            string            `json:"disclosure"`
+	EventHistory          []RetentionPolicy `json:"event_history"`
+	EventStoreDir         string            `json:"event_store_dir"`
+	EventStoreSegmentSize int64             `json:"event_store_segment_size"`
+	EventStoreSegments    int               `json:"event_store_segments"`
+	EventStore            EventStore        `json:"-"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Port: 8888,
+		Realms: []RealmConfig{
+			{Name: defaultRealm},
+		},
+	}
+}
+
+// LoadConfig reads a json config file, realms are required
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := DefaultConfig()
+	c.Realms = nil
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("Error parsing config file %s: %s", path, err)
+	}
+
+	if len(c.Realms) == 0 {
+		return nil, fmt.Errorf("Config file %s without realms", path)
+	}
+	if _, err := NewAuthenticators(c.Authenticators); err != nil {
+		return nil, fmt.Errorf("Error on config file %s: %s", path, err)
+	}
+
+	return c, nil
+}
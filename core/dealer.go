@@ -3,6 +3,8 @@ package core
 import (
 	"fmt"
 	"log"
+	"math/rand"
+	"strings"
 	"sync"
 	"time"
 )
@@ -12,29 +14,127 @@ type Dealer interface {
 	Unregister(Message, *Session)
 	Call(Message, *Session)
 	Yield(Message, *Session)
-	Interrupt(Message, *Session)
 	Cancel(Message, *Session)
+	Error(Message, *Session)
 	RegisterSessionHandlers(map[URI]Handler, *inSession)
+	UnregisterSession(*Session)
 	Handlers() map[URI]Handler
 }
 
+// Call cancel modes
+const (
+	SKIP       = "skip"
+	KILL       = "kill"
+	KILLNOWAIT = "killnowait"
+)
+
+// Shared registration invocation policies
+const (
+	SINGLE     = "single"
+	ROUNDROBIN = "roundrobin"
+	RANDOM     = "random"
+	FIRST      = "first"
+	LAST       = "last"
+)
+
+func validInvokePolicy(policy string) bool {
+	switch policy {
+	case SINGLE, ROUNDROBIN, RANDOM, FIRST, LAST:
+		return true
+	}
+
+	return false
+}
+
 type defaultDealer struct {
-	sessionHandlers map[URI]ID
-	registrations   map[ID]*Session // handler registrations from session callees
+	sessionHandlers map[URI]ID           // exact registrations by procedure
+	patterns        map[uriPattern]ID    // prefix and wildcard registrations
+	prefixes        *uriTrie             // prefix registrations index
+	wildcards       *uriTrie             // wildcard registrations index
+	registrations   map[ID]*registration // handler registrations from session callees
 	reqListeners    *RequestListener
 	mutex           *sync.RWMutex
 	metaEvents      SessionMetaEventHandler
-	activeTasks     map[ID]*task
+	activeTasks     map[requestKey]*task // tasks by callee invocation
+	calls           map[requestKey]*task // tasks by caller call request
+	ids             *idGenerator         // router scope registration ids
+	authorizer      Authorizer
+	callTimeout     time.Duration // default call timeout, void disables it
+	disclosure      string        // caller disclosure policy
+}
+
+// registration groups callees sharing a procedure under its invoke policy
+type registration struct {
+	id        ID
+	procedure URI
+	match     string
+	invoke    string
+	created   time.Time
+	callees   []*Session
+	next      int
+}
+
+// details describes registration on meta api
+func (r *registration) details() map[string]interface{} {
+	return map[string]interface{}{
+		"id":      r.id,
+		"created": r.created.UTC().Format(time.RFC3339Nano),
+		"uri":     r.procedure,
+		"match":   r.match,
+		"invoke":  r.invoke,
+	}
+}
+
+func (r *registration) hasCallee(s *Session) bool {
+	for _, c := range r.callees {
+		if c.ID() == s.ID() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *registration) removeCallee(s *Session) {
+	for i, c := range r.callees {
+		if c.ID() == s.ID() {
+			r.callees = append(r.callees[:i], r.callees[i+1:]...)
+			return
+		}
+	}
+}
+
+// callee picks next callee by invoke policy
+func (r *registration) callee() *Session {
+	switch r.invoke {
+	case LAST:
+		return r.callees[len(r.callees)-1]
+	case ROUNDROBIN:
+		r.next = r.next % len(r.callees)
+		s := r.callees[r.next]
+		r.next++
+		return s
+	case RANDOM:
+		return r.callees[rand.Intn(len(r.callees))]
+	default:
+		return r.callees[0]
+	}
 }
 
-func NewDealer(m SessionMetaEventHandler) *defaultDealer {
+func NewDealer(m SessionMetaEventHandler, a Authorizer) *defaultDealer {
 	d := &defaultDealer{
 		sessionHandlers: make(map[URI]ID),
-		registrations:   make(map[ID]*Session),
+		patterns:        make(map[uriPattern]ID),
+		prefixes:        newURITrie(),
+		wildcards:       newURITrie(),
+		registrations:   make(map[ID]*registration),
 		mutex:           &sync.RWMutex{},
 		reqListeners:    NewRequestListener(),
 		metaEvents:      m,
-		activeTasks:     make(map[ID]*task),
+		activeTasks:     make(map[requestKey]*task),
+		calls:           make(map[requestKey]*task),
+		ids:             newIdGenerator(),
+		authorizer:      a,
 	}
 
 	return d
@@ -46,25 +146,79 @@ func (d *defaultDealer) Register(msg Message, s *Session) {
 	defer d.mutex.Unlock()
 
 	register := msg.(*Register)
-	if _, ok := d.sessionHandlers[register.Procedure]; ok {
-		uri := fmt.Sprintf("%s handler already registered ", register.Procedure)
-		response := &Error{
+	match, _ := register.Options["match"].(string)
+	if match == "" {
+		match = EXACT
+	}
+	invoke, _ := register.Options["invoke"].(string)
+	if invoke == "" {
+		invoke = SINGLE
+	}
+	if !validMatchPolicy(match) || !validInvokePolicy(invoke) {
+		log.Println("Invalid registration policies ", match, invoke)
+		s.Send(&Error{
+			Type:    REGISTER,
 			Request: register.Request,
-			Error:   URI(uri),
-		}
-		s.Send(response)
+			Details: map[string]interface{}{"match": match, "invoke": invoke},
+			Error:   URI("wamp.error.invalid_argument"),
+		})
 		return
 	}
 
-	id := NewId()
-	d.registrations[id] = s
-	d.sessionHandlers[register.Procedure] = id
+	if !authorized(d.authorizer, s, register.Procedure, ActionRegister) {
+		log.Println("Register not authorized ", register.Procedure, s.ID())
+		s.Send(notAuthorized(REGISTER, register.Request, register.Procedure))
+		return
+	}
+
+	var id ID
+	var ok bool
+	if match == EXACT {
+		id, ok = d.sessionHandlers[register.Procedure]
+	} else {
+		id, ok = d.patterns[uriPattern{match: match, uri: register.Procedure}]
+	}
+	if ok {
+		reg := d.registrations[id]
+		if invoke == SINGLE || reg.invoke != invoke || reg.hasCallee(s) {
+			uri := fmt.Sprintf("%s handler already registered ", register.Procedure)
+			log.Println(uri)
+			response := &Error{
+				Type:    REGISTER,
+				Request: register.Request,
+				Details: map[string]interface{}{"invoke": reg.invoke},
+				Error:   URI("wamp.error.procedure_already_exists"),
+			}
+			s.Send(response)
+			return
+		}
+		reg.callees = append(reg.callees, s)
+	} else {
+		id = d.ids.next()
+		reg := &registration{
+			id:        id,
+			procedure: register.Procedure,
+			match:     match,
+			invoke:    invoke,
+			created:   time.Now(),
+			callees:   []*Session{s},
+		}
+		d.registrations[id] = reg
+		switch match {
+		case PREFIX:
+			d.patterns[uriPattern{match: match, uri: register.Procedure}] = id
+			d.prefixes.add(prefixKeys(string(register.Procedure)), id)
+		case WILDCARD:
+			d.patterns[uriPattern{match: match, uri: register.Procedure}] = id
+			d.wildcards.add(wildcardKeys(string(register.Procedure)), id)
+		default:
+			d.sessionHandlers[register.Procedure] = id
+		}
+		d.fireMetaEvent(s, Topic("wamp.registration.on_create"), reg, reg.details())
+	}
+
 	s.addRegistration(id, register.Procedure)
-	d.metaEvents.Fire(
-		s.ID(),
-		URI("wampire.registration.on_register"),
-		map[string]interface{}{},
-	)
+	d.fireMetaEvent(s, Topic("wamp.registration.on_register"), d.registrations[id], id)
 
 	if s.ID() == PeerID("internal") {
 		return
@@ -80,13 +234,14 @@ func (d *defaultDealer) Unregister(msg Message, s *Session) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	unregister := msg.(*Unregister)
-	_, ok := d.registrations[unregister.Registration]
-	if !ok {
-		uri := fmt.Sprintf("%d handler not registered ", unregister.Request)
+	reg, ok := d.registrations[unregister.Registration]
+	if !ok || !reg.hasCallee(s) {
+		uri := fmt.Sprintf("%d handler not registered ", unregister.Registration)
 		log.Println(uri)
 		response := &Error{
+			Type:    UNREGISTER,
 			Request: unregister.Request,
-			Error:   URI(uri),
+			Error:   URI("wamp.error.no_such_registration"),
 		}
 		s.Send(response)
 		return
@@ -104,144 +259,414 @@ func (d *defaultDealer) Unregister(msg Message, s *Session) {
 		return
 	}
 
-	_, ok = d.sessionHandlers[uri]
-	if !ok {
-		uri := fmt.Sprintf("%d peerHandlers not found  %s", unregister.Registration, uri)
-		log.Println(uri)
-		response := &Error{
-			Request: unregister.Request,
-			Error:   URI(uri),
+	d.removeCallee(reg, s)
+	//unregister uri from session
+	s.unregister(uri)
+	s.removeRegistration(unregister.Registration)
+
+	response := &Unregistered{
+		Request: unregister.Request,
+	}
+	s.Send(response)
+}
+
+// UnregisterSession removes leaving session from its registrations,
+// callers of its pending invocations are canceled and its pending
+// calls interrupted
+func (d *defaultDealer) UnregisterSession(s *Session) {
+	d.mutex.Lock()
+	for id, uri := range s.getRegistrations() {
+		if reg, ok := d.registrations[id]; ok && reg.hasCallee(s) {
+			d.removeCallee(reg, s)
 		}
-		s.Send(response)
+		s.unregister(uri)
+		s.removeRegistration(id)
+	}
+
+	// pending tasks on leaving callee or caller are removed
+	calleeTasks, callerTasks := []*task{}, []*task{}
+	for _, task := range d.activeTasks {
+		if task.callee.ID() == s.ID() {
+			calleeTasks = append(calleeTasks, task)
+		} else if task.session.ID() == s.ID() {
+			callerTasks = append(callerTasks, task)
+		} else {
+			continue
+		}
+		d.stopTask(task)
+	}
+	d.mutex.Unlock()
+
+	for _, task := range calleeTasks {
+		log.Println("Callee left, canceling call ", task.request, task.procedure)
+		task.interrupted.Do(func() { close(task.terminate) })
+		task.session.Send(canceled(task.request, map[string]interface{}{"message": "callee left"}, nil, nil))
+	}
+	for _, task := range callerTasks {
+		log.Println("Caller left, interrupting invocation ", task.invocation, task.procedure)
+		d.interrupt(task, KILLNOWAIT)
+	}
+}
+
+// removeCallee removes session from registration, void registrations
+// are deleted, mutex must be held
+func (d *defaultDealer) removeCallee(reg *registration, s *Session) {
+	reg.removeCallee(s)
+	d.fireMetaEvent(s, Topic("wamp.registration.on_unregister"), reg, reg.id)
+	if len(reg.callees) > 0 {
 		return
 	}
 
-	//delete uri s
-	delete(d.sessionHandlers, uri)
-	//delete dealer registrations
-	delete(d.registrations, unregister.Registration)
-	//unregister uri from session
-	s.unregister(uri)
+	//delete void registration and its index
+	switch reg.match {
+	case PREFIX:
+		delete(d.patterns, uriPattern{match: reg.match, uri: reg.procedure})
+		d.prefixes.remove(prefixKeys(string(reg.procedure)), reg.id)
+	case WILDCARD:
+		delete(d.patterns, uriPattern{match: reg.match, uri: reg.procedure})
+		d.wildcards.remove(wildcardKeys(string(reg.procedure)), reg.id)
+	default:
+		delete(d.sessionHandlers, reg.procedure)
+	}
+	delete(d.registrations, reg.id)
+	d.fireMetaEvent(s, Topic("wamp.registration.on_delete"), reg, reg.id)
+}
 
+// fireMetaEvent publishes registration meta event with (session, registration)
+// arguments, registration id and procedure keyword arguments
+func (d *defaultDealer) fireMetaEvent(s *Session, event Topic, reg *registration, argument interface{}) {
 	d.metaEvents.Fire(
 		s.ID(),
-		URI("wampire.registration.on_unregister"),
-		map[string]interface{}{},
+		event,
+		[]interface{}{s.sessionID, argument},
+		map[string]interface{}{"registration": reg.id, "procedure": reg.procedure, "match": reg.match},
 	)
-
-	response := &Unregistered{
-		Request: unregister.Request,
-	}
-	s.Send(response)
 }
 
 func (d *defaultDealer) Call(msg Message, s *Session) {
 	call := msg.(*Call)
-	registration, ok := d.sessionHandlers[call.Procedure]
+	if !authorized(d.authorizer, s, call.Procedure, ActionCall) {
+		log.Println("Call not authorized ", call.Procedure, s.ID())
+		s.Send(notAuthorized(CALL, call.Request, call.Procedure))
+		return
+	}
+
+	disclose, err := discloseIdentity(d.disclosure, call.Options)
+	if err != nil {
+		log.Println("Call disclosure not allowed ", call.Procedure, s.ID())
+		s.Send(disclosureDisallowed(CALL, call.Request, err))
+		return
+	}
+
+	d.mutex.Lock()
+	reg, ok := d.match(call.Procedure)
+	var calleeSession *Session
+	if ok {
+		calleeSession = reg.callee()
+	}
+	d.mutex.Unlock()
 	if !ok {
 		uri := "Registration not found on sessionHandlers"
 		log.Print(uri, msg.MsgType())
 		response := &Error{
-			Error: URI(uri),
+			Type:    CALL,
+			Request: call.Request,
+			Error:   URI("wamp.error.no_such_procedure"),
 		}
 		s.Send(response)
 		return
 	}
 
-	// Forward as invocation to peer calleee
+	// only call options relevant to callees are forwarded
+	details := map[string]interface{}{}
+	for _, k := range []string{"receive_progress", "timeout"} {
+		if v, ok := call.Options[k]; ok {
+			details[k] = v
+		}
+	}
+	if reg.match != EXACT {
+		details["procedure"] = call.Procedure
+	}
+	// router procedures always know its caller
+	if disclose || calleeSession.ID() == PeerID("internal") {
+		for k, v := range s.disclose("caller") {
+			details[k] = v
+		}
+	}
+
+	// Forward as invocation to peer calleee, invocation ids are on callee session scope
 	invocation := &Invocation{
-		Request:      call.Request,
-		Registration: registration,
-		Details:      call.Options,
+		Request:      calleeSession.nextRequestId(),
+		Registration: reg.id,
+		Details:      details,
 		Arguments:    call.Arguments,
 		ArgumentsKw:  call.ArgumentsKw,
 	}
 
-	log.Println("Invocation:", call.Procedure, "Request is ", call.Request, "origin peer ", s.ID())
-	calleeSession, ok := d.registrations[registration]
-	if !ok {
-		uri := "Registration not found "
-		log.Print(uri, msg.MsgType())
-		response := &Error{
-			Error: URI(uri),
-		}
-		s.Send(response)
-		return
-	}
+	log.Println("Invocation:", call.Procedure, "Request is ", call.Request, "origin peer ", s.ID(), "callee ", calleeSession.ID())
 
 	// register call request in active task map
-	task := newTask(s, invocation.Request, call.Procedure, false)
-	d.addTask(task)
+	progressive, _ := call.Options["receive_progress"].(bool)
+	task := newTask(s, call.Request, call.Procedure, progressive)
+	task.callee = calleeSession
+	task.invocation = invocation.Request
+	d.addTask(task, d.timeout(call))
 
-	// Handle Invocation
-	err := calleeSession.do(invocation)
+	// Handle Invocation, callee failures are returned as invocation errors
+	err = calleeSession.do(invocation)
 	if err != nil {
 		log.Println("Error calleeSession do", err, invocation)
-		response := &Error{
-			Error:   URI("calleeSession invocation do Error"),
-			Details: map[string]interface{}{"error": err},
-		}
-		s.Send(response)
+		d.removeTask(task)
+		s.Send(&Error{
+			Type:      CALL,
+			Request:   call.Request,
+			Details:   map[string]interface{}{},
+			Error:     URI("wamp.error.no_such_registration"),
+			Arguments: []interface{}{err.Error()},
+		})
+	}
+}
+
+// timeout returns call timeout option in milliseconds, dealer default if not set
+func (d *defaultDealer) timeout(call *Call) time.Duration {
+	if ms, ok := toInt(call.Options["timeout"]); ok && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+
+	return d.callTimeout
+}
+
+// timeoutTask interrupts expired task callee and reports timeout to caller,
+// canceled tasks waiting callee error are reported as canceled
+func (d *defaultDealer) timeoutTask(task *task) {
+	d.mutex.Lock()
+	active, ok := d.activeTasks[task.calleeKey()]
+	if !ok || active != task {
+		d.mutex.Unlock()
 		return
 	}
+	d.stopTask(task)
+	wasCanceled := task.canceled
+	d.mutex.Unlock()
+
+	log.Println("Call timeout on task ", task.request, task.procedure)
+	if wasCanceled {
+		task.session.Send(canceled(task.request, nil, nil, nil))
+		return
+	}
+
+	d.interrupt(task, KILLNOWAIT)
+	task.session.Send(&Error{
+		Type:    CALL,
+		Request: task.request,
+		Details: map[string]interface{}{},
+		Error:   URI("wamp.error.timeout"),
+	})
+}
+
+// match resolves procedure registration, exact registrations first,
+// then longest prefix and then most specific wildcard
+func (d *defaultDealer) match(procedure URI) (*registration, bool) {
+	if id, ok := d.sessionHandlers[procedure]; ok {
+		return d.registrations[id], true
+	}
+
+	ids := map[ID]bool{}
+	d.prefixes.matchPrefix(prefixKeys(string(procedure)), ids)
+	if reg := d.longestMatch(ids); reg != nil {
+		return reg, true
+	}
+
+	d.wildcards.matchWildcard(wildcardKeys(string(procedure)), ids)
+	if reg := d.longestMatch(ids); reg != nil {
+		return reg, true
+	}
+
+	return nil, false
 }
 
+// longestMatch picks registration with longest procedure pattern,
+// void wildcard components are not counted
+func (d *defaultDealer) longestMatch(ids map[ID]bool) *registration {
+	var match *registration
+	for id := range ids {
+		reg := d.registrations[id]
+		if match == nil || patternLength(reg.procedure) > patternLength(match.procedure) ||
+			patternLength(reg.procedure) == patternLength(match.procedure) && reg.procedure > match.procedure {
+			match = reg
+		}
+	}
+
+	return match
+}
+
+func patternLength(uri URI) int {
+	return len(strings.Replace(string(uri), "..", ".", -1))
+}
+
+// Yield forwards callee results to caller, progressive results keep task
+// active until final yield, callers not requesting progress get an error
 func (d *defaultDealer) Yield(msg Message, s *Session) {
 	yield := msg.(*Yield)
+	progress, _ := yield.Options["progress"].(bool)
 
-	d.mutex.RLock()
-	task, ok := d.activeTasks[yield.Request]
-	d.mutex.RUnlock()
+	d.mutex.Lock()
+	task, ok := d.activeTasks[requestKey{s.ID(), yield.Request}]
 	if !ok {
+		d.mutex.Unlock()
 		log.Println("Error, task not found! ", yield.Request)
 		return
 	}
+	// kill canceled tasks discard progressive results, final result
+	// replies caller with canceled error
+	if task.canceled {
+		if !progress {
+			d.stopTask(task)
+		}
+		d.mutex.Unlock()
+		if !progress {
+			task.session.Send(canceled(task.request, nil, nil, nil))
+		}
+		return
+	}
+	if !progress || !task.progressive {
+		d.stopTask(task)
+	}
+	d.mutex.Unlock()
 
+	if progress && !task.progressive {
+		log.Println("Progressive result on call without receive_progress ", yield.Request)
+		d.interrupt(task, KILLNOWAIT)
+		task.session.Send(&Error{
+			Type:    CALL,
+			Request: task.request,
+			Details: map[string]interface{}{"message": "progressive result not requested"},
+			Error:   URI("wamp.error.invalid_argument"),
+		})
+		return
+	}
+
+	details := map[string]interface{}{}
+	if progress {
+		details["progress"] = true
+	}
 	response := &Result{
-		Request:     yield.Request,
-		Details:     yield.Options,
+		Request:     task.request,
+		Details:     details,
 		Arguments:   yield.Arguments,
 		ArgumentsKw: yield.ArgumentsKw,
 	}
 	task.session.Send(response)
-
-	if !task.progressive {
-		d.removeTask(task)
-	}
 }
 
+// Cancel interrupts caller task by cancel mode, skip only discards callee
+// response, kill waits callee error and killnowait replies immediately
 func (d *defaultDealer) Cancel(msg Message, s *Session) {
 	cancel := msg.(*Cancel)
+	mode, _ := cancel.Options["mode"].(string)
+	if mode == "" {
+		mode = KILLNOWAIT
+	}
+	if mode != SKIP && mode != KILL && mode != KILLNOWAIT {
+		log.Println("Invalid cancel mode ", mode)
+		s.Send(&Error{
+			Type:    CANCEL,
+			Request: cancel.Request,
+			Details: map[string]interface{}{"mode": mode},
+			Error:   URI("wamp.error.invalid_argument"),
+		})
+		return
+	}
 
-	log.Println("Canceling task ", cancel.Request)
-	task, ok := d.activeTasks[cancel.Request]
-	if !ok {
+	log.Println("Canceling task ", cancel.Request, " mode ", mode)
+	d.mutex.Lock()
+	task, ok := d.calls[requestKey{s.ID(), cancel.Request}]
+	if !ok || task.canceled {
+		d.mutex.Unlock()
 		log.Println("Current task not found! ", cancel.Request)
 		return
 	}
+	task.canceled = true
+	if mode != KILL {
+		d.stopTask(task)
+	}
+	d.mutex.Unlock()
 
-	// close task channel, don't handle response here
-	close(task.terminate)
-
-	d.removeTask(task)
+	if mode != SKIP {
+		d.interrupt(task, mode)
+	}
+	if mode != KILL {
+		s.Send(canceled(task.request, map[string]interface{}{}, nil, nil))
+	}
 }
 
-// As cancel workaround
-func (d *defaultDealer) Interrupt(msg Message, s *Session) {
-	interrupt := msg.(*Interrupt)
+// Error forwards callee invocation errors to caller preserving error uri
+// and payload, canceled tasks reply caller with canceled error
+func (d *defaultDealer) Error(msg Message, s *Session) {
+	e := msg.(*Error)
+	if e.Type != INVOCATION {
+		log.Println("Unexpected error type from callee ", e.Type)
+		return
+	}
 
-	d.mutex.RLock()
-	task, ok := d.activeTasks[interrupt.Request]
-	d.mutex.RUnlock()
+	d.mutex.Lock()
+	task, ok := d.activeTasks[requestKey{s.ID(), e.Request}]
 	if !ok {
-		log.Println("Error, task not found! ", interrupt.Request)
+		d.mutex.Unlock()
+		log.Println("Error, task not found! ", e.Request, e.Error)
 		return
 	}
-	task.session.Send(interrupt)
+	d.stopTask(task)
+	d.mutex.Unlock()
+
+	if task.canceled {
+		task.session.Send(canceled(task.request, e.Details, e.Arguments, e.ArgumentsKw))
+		return
+	}
+
+	details := e.Details
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	task.session.Send(&Error{
+		Type:        CALL,
+		Request:     task.request,
+		Details:     details,
+		Error:       e.Error,
+		Arguments:   e.Arguments,
+		ArgumentsKw: e.ArgumentsKw,
+	})
+}
+
+// interrupt signals task termination to local handlers and remote callees,
+// tasks are interrupted once
+func (d *defaultDealer) interrupt(task *task, mode string) {
+	task.interrupted.Do(func() {
+		close(task.terminate)
+		if task.callee.ID() == PeerID("internal") {
+			return
+		}
+
+		task.callee.Send(&Interrupt{
+			Request: task.invocation,
+			Options: map[string]interface{}{"mode": mode},
+		})
+	})
+}
 
-	d.removeTask(task)
+func canceled(request ID, details map[string]interface{}, args []interface{}, kw map[string]interface{}) *Error {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
 
-	return
+	return &Error{
+		Type:        CALL,
+		Request:     request,
+		Details:     details,
+		Error:       URI("wamp.error.canceled"),
+		Arguments:   args,
+		ArgumentsKw: kw,
+	}
 }
 
 func (d *defaultDealer) RegisterSessionHandlers(handlers map[URI]Handler, s *inSession) {
@@ -259,37 +684,284 @@ func (d *defaultDealer) Handlers() map[URI]Handler {
 		"wampire.core.dealer.dump":         d.dumpDealer,
 		"wampire.core.long.duration.call":  d.longDurationTask,
 		"wampire.core.dealer.active.tasks": d.dumpActiveTasks,
+		"wampire.registration.revoke":      d.revokeRegistration,
+		"wamp.registration.list":           d.metaList,
+		"wamp.registration.lookup":         d.metaLookup,
+		"wamp.registration.match":          d.metaMatch,
+		"wamp.registration.get":            d.metaGet,
+		"wamp.registration.list_callees":   d.metaListCallees,
+		"wamp.registration.count_callees":  d.metaCountCallees,
 	}
 }
 
-func (d *defaultDealer) addTask(task *task) {
+// revokeRegistration removes registration callees, or session id second
+// argument only, sending router unregistered with reason keyword argument,
+// returns revoked session ids
+func (d *defaultDealer) revokeRegistration(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
 	d.mutex.Lock()
-	log.Println("Add invocation request ", task.request)
-	d.activeTasks[task.request] = task
+	reg, err := d.registrationArgument(inv)
+	if err != nil {
+		d.mutex.Unlock()
+		return nil, err
+	}
+	if reg.callees[0].ID() == PeerID("internal") {
+		d.mutex.Unlock()
+		return nil, newHandlerError(URI("wamp.error.invalid_argument"), "Router registrations cannot be revoked")
+	}
+	session, err := sessionArgument(inv)
+	if err != nil {
+		d.mutex.Unlock()
+		return nil, err
+	}
+	reason := revokeReason(inv, URI("wampire.registration.revoked"))
+
+	sessions := []*Session{}
+	for _, s := range append([]*Session{}, reg.callees...) {
+		if session != 0 && s.sessionID != session {
+			continue
+		}
+		log.Println("Revoking registration ", reg.id, " on session ", s.ID(), reason)
+		d.removeCallee(reg, s)
+		s.unregister(reg.procedure)
+		s.removeRegistration(reg.id)
+		sessions = append(sessions, s)
+	}
+	d.mutex.Unlock()
+	if session != 0 && len(sessions) == 0 {
+		return nil, newHandlerError(URI("wamp.error.no_such_session"))
+	}
+
+	// notify revoked callees out of dealer lock
+	revoked := []interface{}{}
+	for _, s := range sessions {
+		s.Send(&Unregistered{
+			Details: map[string]interface{}{"registration": reg.id, "reason": reason},
+		})
+		revoked = append(revoked, s.sessionID)
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{revoked},
+	}, nil
+}
+
+// metaList returns registration ids by match policy, router
+// procedures are not listed
+func (d *defaultDealer) metaList(msg Message) (Message, error) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	list := map[string]interface{}{}
+	ids := map[string][]interface{}{EXACT: {}, PREFIX: {}, WILDCARD: {}}
+	for id, reg := range d.registrations {
+		if reg.callees[0].ID() == PeerID("internal") {
+			continue
+		}
+		ids[reg.match] = append(ids[reg.match], id)
+	}
+	for match, l := range ids {
+		list[match] = l
+	}
+
+	return &Yield{
+		Request:   msg.(*Invocation).Request,
+		Arguments: []interface{}{list},
+	}, nil
+}
+
+// metaLookup returns registration id on procedure with match option, nil if not found
+func (d *defaultDealer) metaLookup(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	procedure, err := procedureArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+	match := EXACT
+	if len(inv.Arguments) > 1 {
+		if options, ok := inv.Arguments[1].(map[string]interface{}); ok {
+			if m, ok := options["match"].(string); ok && m != "" {
+				match = m
+			}
+		}
+	}
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var id ID
+	var ok bool
+	if match == EXACT {
+		id, ok = d.sessionHandlers[procedure]
+	} else {
+		id, ok = d.patterns[uriPattern{match: match, uri: procedure}]
+	}
+	var result interface{}
+	if ok {
+		result = id
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{result},
+	}, nil
+}
+
+// metaMatch returns registration id invoked on procedure calls, nil if none
+func (d *defaultDealer) metaMatch(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	procedure, err := procedureArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	var result interface{}
+	if reg, ok := d.match(procedure); ok {
+		result = reg.id
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{result},
+	}, nil
+}
+
+// metaGet returns registration details
+func (d *defaultDealer) metaGet(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	reg, err := d.registrationArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{reg.details()},
+	}, nil
+}
+
+// metaListCallees returns registered session ids
+func (d *defaultDealer) metaListCallees(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	reg, err := d.registrationArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+	callees := []interface{}{}
+	for _, s := range reg.callees {
+		callees = append(callees, s.sessionID)
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{callees},
+	}, nil
+}
+
+// metaCountCallees returns registered sessions count
+func (d *defaultDealer) metaCountCallees(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	reg, err := d.registrationArgument(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{len(reg.callees)},
+	}, nil
+}
+
+// registrationArgument returns registration on first invocation argument,
+// mutex must be held
+func (d *defaultDealer) registrationArgument(inv *Invocation) (*registration, error) {
+	if len(inv.Arguments) < 1 {
+		return nil, newHandlerError(URI("wamp.error.invalid_argument"), "Void registration argument")
+	}
+	id, ok := toInt(inv.Arguments[0])
+	if !ok {
+		return nil, newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid registration argument %v", inv.Arguments[0]))
+	}
+	reg, ok := d.registrations[ID(id)]
+	if !ok {
+		return nil, newHandlerError(URI("wamp.error.no_such_registration"))
+	}
+
+	return reg, nil
+}
+
+func procedureArgument(inv *Invocation) (URI, error) {
+	if len(inv.Arguments) < 1 {
+		return "", newHandlerError(URI("wamp.error.invalid_argument"), "Void procedure argument")
+	}
+	procedure, ok := inv.Arguments[0].(string)
+	if !ok {
+		return "", newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid procedure argument %v", inv.Arguments[0]))
+	}
+
+	return URI(procedure), nil
+}
+
+// addTask registers active task, expiring it after timeout if not void
+func (d *defaultDealer) addTask(task *task, timeout time.Duration) {
+	d.mutex.Lock()
+	log.Println("Add invocation request ", task.invocation, " on call ", task.request)
+	d.activeTasks[task.calleeKey()] = task
+	d.calls[task.callerKey()] = task
+	if timeout > 0 {
+		task.timer = time.AfterFunc(timeout, func() { d.timeoutTask(task) })
+	}
 	d.mutex.Unlock()
 }
 
 func (d *defaultDealer) removeTask(task *task) {
 	d.mutex.Lock()
-	log.Println("Remove invocation request ", task.request)
-	delete(d.activeTasks, task.request)
+	d.stopTask(task)
 	d.mutex.Unlock()
 }
 
+// stopTask removes task and its timer, mutex must be held
+func (d *defaultDealer) stopTask(task *task) {
+	log.Println("Remove invocation request ", task.invocation, " on call ", task.request)
+	if task.timer != nil {
+		task.timer.Stop()
+	}
+	delete(d.activeTasks, task.calleeKey())
+	if d.calls[task.callerKey()] == task {
+		delete(d.calls, task.callerKey())
+	}
+}
+
 func (d *defaultDealer) dumpDealer(msg Message) (Message, error) {
 	d.mutex.RLock()
-	sessions := d.sessionHandlers
-	registrations := d.registrations
-	d.mutex.RUnlock()
+	defer d.mutex.RUnlock()
 
 	list := map[string]interface{}{}
-	for uri, id := range sessions {
-		list[string(uri)] = id
-	}
-
 	regs := map[string]interface{}{}
-	for id, s := range registrations {
-		regs[fmt.Sprintf("%d", id)] = s.ID()
+	for id, reg := range d.registrations {
+		list[string(reg.procedure)] = id
+		callees := []interface{}{}
+		for _, s := range reg.callees {
+			callees = append(callees, s.ID())
+		}
+		regs[fmt.Sprintf("%d", id)] = map[string]interface{}{
+			"match":   reg.match,
+			"invoke":  reg.invoke,
+			"callees": callees,
+		}
 	}
 	inv := msg.(*Invocation)
 	kw := map[string]interface{}{
@@ -306,51 +978,42 @@ func (d *defaultDealer) dumpDealer(msg Message) (Message, error) {
 func (d *defaultDealer) longDurationTask(msg Message) (Message, error) {
 	invocation := msg.(*Invocation)
 	log.Println("Invoking long duration task")
-	task, ok := d.activeTasks[invocation.Request]
+	d.mutex.RLock()
+	task, ok := d.activeTasks[requestKey{PeerID("internal"), invocation.Request}]
+	d.mutex.RUnlock()
 	if !ok {
 		log.Println("Current task not found! ")
+		return nil, fmt.Errorf("Task %d not found", invocation.Request)
 	}
 
-	updateTickerDuration := time.Second * 50
-	if v, ok := invocation.Details["receive_progress"]; ok && v.(bool) {
-		updateTickerDuration = time.Second * 5
+	// progress updates only if caller requested them
+	updateTicker := time.NewTicker(time.Second * 5)
+	defer updateTicker.Stop()
+	if !task.progressive {
+		updateTicker.Stop()
 	}
-	updateTicker := time.NewTicker(updateTickerDuration)
 	timeout := time.NewTimer(time.Second * 50)
 	loopIterations := 0
 	for {
 		select {
 		case <-task.terminate:
 			log.Println("Canceled task ", invocation.Request)
-			return &Interrupt{
+			return &Error{
+				Type:    INVOCATION,
 				Request: invocation.Request,
+				Details: map[string]interface{}{},
+				Error:   URI("wamp.error.canceled"),
 			}, nil
 		case <-updateTicker.C:
 			loopIterations++
-			d.mutex.RLock()
-			task, ok := d.activeTasks[invocation.Request]
-			d.mutex.RUnlock()
-			if !ok {
-				log.Println("Error, task not found! ", invocation.Request)
-				continue
-			}
 			log.Println("Updating task ", invocation.Request)
-			update := &Yield{
+			d.Yield(&Yield{
 				Request:     invocation.Request,
+				Options:     map[string]interface{}{"progress": true},
 				ArgumentsKw: map[string]interface{}{"update": loopIterations},
-			}
-
-			task.session.Send(update)
+			}, task.callee)
 		case <-timeout.C:
 			log.Println("done long duration task done")
-			d.mutex.Lock()
-			task, ok := d.activeTasks[invocation.Request]
-			d.mutex.Unlock()
-			if !ok {
-				log.Println("Error, task not found! ", invocation.Request)
-				continue
-			}
-			task.progressive = false
 
 			return &Yield{
 				Request:   invocation.Request,
@@ -366,9 +1029,9 @@ func (d *defaultDealer) dumpActiveTasks(msg Message) (Message, error) {
 
 	inv := msg.(*Invocation)
 	activeTasks := []interface{}{}
-	for taskID, _ := range d.activeTasks {
-		if taskID != inv.Request {
-			activeTasks = append(activeTasks, taskID)
+	for key, task := range d.activeTasks {
+		if key != (requestKey{PeerID("internal"), inv.Request}) {
+			activeTasks = append(activeTasks, task.request)
 		}
 	}
 
@@ -381,12 +1044,24 @@ func (d *defaultDealer) dumpActiveTasks(msg Message) (Message, error) {
 	}, nil
 }
 
+// requestKey identifies session scope requests
+type requestKey struct {
+	peer    PeerID
+	request ID
+}
+
+// task correlates caller call request with callee invocation
 type task struct {
 	session     *Session
+	callee      *Session
 	request     ID
+	invocation  ID
 	procedure   URI
 	progressive bool
+	canceled    bool
 	terminate   chan struct{}
+	interrupted *sync.Once
+	timer       *time.Timer
 }
 
 func newTask(s *Session, id ID, uri URI, p bool) *task {
@@ -396,5 +1071,14 @@ func newTask(s *Session, id ID, uri URI, p bool) *task {
 		procedure:   uri,
 		progressive: p,
 		terminate:   make(chan struct{}),
+		interrupted: &sync.Once{},
 	}
 }
+
+func (t *task) callerKey() requestKey {
+	return requestKey{t.session.ID(), t.request}
+}
+
+func (t *task) calleeKey() requestKey {
+	return requestKey{t.callee.ID(), t.invocation}
+}
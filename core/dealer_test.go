@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"log"
 	"testing"
 	"time"
@@ -8,7 +9,7 @@ import (
 
 func TestDealerCallBasicFlowOnInternalPeerInvocation(t *testing.T) {
 	m := &fakeSessionMetaEventsHandler{}
-	d := NewDealer(m)
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
 	sessionA := NewSession(NewFakePeer(PeerID("123")))
 	done := make(chan struct{})
 	//go sessionLoop(sessionA, done)
@@ -21,7 +22,7 @@ func TestDealerCallBasicFlowOnInternalPeerInvocation(t *testing.T) {
 		for {
 			select {
 			case msg := <-exp.Receive():
-				go d.Yield(msg, sessionA)
+				go d.Yield(msg, exp)
 			case <-exit:
 				return
 			}
@@ -91,3 +92,606 @@ func fooHandler(msg Message) (Message, error) {
 		Arguments: []interface{}{"okiDoki"},
 	}, nil
 }
+
+func TestDealerSharedRegistrationRoundRobin(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+
+	workers := []*Session{}
+	var registration ID
+	for i := 0; i < 3; i++ {
+		w := NewSession(NewFakePeer(PeerID(fmt.Sprintf("worker%d", i))))
+		d.Register(&Register{Request: NewId(), Procedure: URI("com.myapp.job"), Options: map[string]interface{}{"invoke": ROUNDROBIN}}, w)
+		r := <-w.Receive()
+		if r.MsgType() != REGISTERED {
+			t.Fatal("Unexpected register response ", r.MsgType())
+		}
+		if registration != 0 && r.(*Registered).Registration != registration {
+			t.Error("Expected shared registration ID")
+		}
+		registration = r.(*Registered).Registration
+		workers = append(workers, w)
+	}
+
+	// single invocation policy conflicts with roundrobin registration
+	s := NewSession(NewFakePeer(PeerID("single")))
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.job")}, s)
+	r := <-s.Receive()
+	if r.MsgType() != ERROR || r.(*Error).Error != URI("wamp.error.procedure_already_exists") {
+		t.Error("Expected procedure already exists error ", r)
+	}
+
+	for i := 0; i < 6; i++ {
+		d.Call(&Call{Request: ID(100 + i), Procedure: URI("com.myapp.job")}, caller)
+		r := <-workers[i%3].Receive()
+		inv, ok := r.(*Invocation)
+		if !ok || inv.Request != ID(i/3+1) || inv.Registration != registration {
+			t.Error("Unexpected invocation on worker ", i%3, r)
+		}
+	}
+
+	// remaining callees keep serving the procedure
+	for i := 0; i < 2; i++ {
+		d.Unregister(&Unregister{Request: NewId(), Registration: registration}, workers[i])
+		if r := <-workers[i].Receive(); r.MsgType() != UNREGISTERED {
+			t.Error("Unexpected unregister response ", r.MsgType())
+		}
+	}
+	d.Call(&Call{Request: ID(200), Procedure: URI("com.myapp.job")}, caller)
+	if r := <-workers[2].Receive(); r.MsgType() != INVOCATION {
+		t.Error("Unexpected message on last worker ", r.MsgType())
+	}
+}
+
+func TestDealerPatternRegistrationLongestMatch(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+
+	callees := map[URI]*Session{}
+	registrations := []*Register{
+		{Request: ID(1), Procedure: URI("com.myapp."), Options: map[string]interface{}{"match": PREFIX}},
+		{Request: ID(2), Procedure: URI("com.myapp.user."), Options: map[string]interface{}{"match": PREFIX}},
+		{Request: ID(3), Procedure: URI("com..user.get"), Options: map[string]interface{}{"match": WILDCARD}},
+		{Request: ID(4), Procedure: URI("com.myapp.user.get")},
+	}
+	for _, reg := range registrations {
+		s := NewSession(NewFakePeer(PeerID(reg.Procedure)))
+		d.Register(reg, s)
+		if r := <-s.Receive(); r.MsgType() != REGISTERED {
+			t.Fatal("Unexpected register response ", r.MsgType())
+		}
+		callees[reg.Procedure] = s
+	}
+
+	calls := map[URI]URI{
+		URI("com.myapp.user.get"):    URI("com.myapp.user.get"),
+		URI("com.myapp.user.delete"): URI("com.myapp.user."),
+		URI("com.myapp.order.get"):   URI("com.myapp."),
+		URI("com.other.user.get"):    URI("com..user.get"),
+	}
+	for procedure, registered := range calls {
+		d.Call(&Call{Request: NewId(), Procedure: procedure, Options: map[string]interface{}{}}, caller)
+		r := <-callees[registered].Receive()
+		inv, ok := r.(*Invocation)
+		if !ok {
+			t.Fatal("Unexpected message type ", r.MsgType())
+		}
+		if registered != procedure && inv.Details["procedure"] != procedure {
+			t.Error("Expected concrete procedure on invocation details ", inv.Details)
+		}
+	}
+
+	d.Call(&Call{Request: ID(9), Procedure: URI("org.myapp.user.get")}, caller)
+	r := <-caller.Receive()
+	if r.MsgType() != ERROR || r.(*Error).Error != URI("wamp.error.no_such_procedure") {
+		t.Error("Expected no such procedure error ", r)
+	}
+}
+
+func TestDealerCallTimeout(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	d.callTimeout = time.Millisecond * 50
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+	callee := NewSession(NewFakePeer(PeerID("callee")))
+
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.slow")}, callee)
+	if r := <-callee.Receive(); r.MsgType() != REGISTERED {
+		t.Fatal("Unexpected register response ", r.MsgType())
+	}
+
+	// call timeout option overrides dealer default
+	d.Call(&Call{Request: ID(2), Procedure: URI("com.myapp.slow"), Options: map[string]interface{}{"timeout": float64(10)}}, caller)
+	invocation := receiveInvocation(t, callee)
+
+	r := <-callee.Receive()
+	interrupt, ok := r.(*Interrupt)
+	if !ok || interrupt.Request != invocation {
+		t.Error("Expected interrupt on callee ", r)
+	}
+	r = <-caller.Receive()
+	if e, ok := r.(*Error); !ok || e.Type != CALL || e.Request != ID(2) || e.Error != URI("wamp.error.timeout") {
+		t.Error("Expected timeout error on caller ", r)
+	}
+
+	d.mutex.RLock()
+	tasks := len(d.activeTasks)
+	d.mutex.RUnlock()
+	if tasks != 0 {
+		t.Error("Unexpected active tasks after timeout ", tasks)
+	}
+
+	// late yields are discarded
+	d.Yield(&Yield{Request: invocation}, callee)
+
+	// yield before deadline stops task timer
+	d.Call(&Call{Request: ID(3), Procedure: URI("com.myapp.slow")}, caller)
+	invocation = receiveInvocation(t, callee)
+	d.Yield(&Yield{Request: invocation, Arguments: []interface{}{"done"}}, callee)
+	if r := <-caller.Receive(); r.MsgType() != RESULT {
+		t.Error("Unexpected call response ", r.MsgType())
+	}
+	select {
+	case r := <-caller.Receive():
+		t.Error("Unexpected message after result ", r.MsgType())
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestDealerCancelModes(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+	callee := NewSession(NewFakePeer(PeerID("callee")))
+
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.report")}, callee)
+	if r := <-callee.Receive(); r.MsgType() != REGISTERED {
+		t.Fatal("Unexpected register response ", r.MsgType())
+	}
+
+	// skip does not interrupt callee
+	d.Call(&Call{Request: ID(10), Procedure: URI("com.myapp.report")}, caller)
+	invocation := receiveInvocation(t, callee)
+	d.Cancel(&Cancel{Request: ID(10), Options: map[string]interface{}{"mode": SKIP}}, caller)
+	if r := <-caller.Receive(); r.MsgType() != ERROR || r.(*Error).Error != URI("wamp.error.canceled") {
+		t.Error("Expected canceled error on skip ", r)
+	}
+	d.Yield(&Yield{Request: invocation}, callee)
+
+	// killnowait interrupts callee and replies caller immediately
+	d.Call(&Call{Request: ID(11), Procedure: URI("com.myapp.report")}, caller)
+	invocation = receiveInvocation(t, callee)
+	d.Cancel(&Cancel{Request: ID(11), Options: map[string]interface{}{}}, caller)
+	r := <-callee.Receive()
+	if i, ok := r.(*Interrupt); !ok || i.Request != invocation || i.Options["mode"] != KILLNOWAIT {
+		t.Error("Expected killnowait interrupt on callee ", r)
+	}
+	if r := <-caller.Receive(); r.MsgType() != ERROR || r.(*Error).Error != URI("wamp.error.canceled") {
+		t.Error("Expected canceled error on killnowait ", r)
+	}
+
+	// kill waits callee error before replying caller
+	d.Call(&Call{Request: ID(12), Procedure: URI("com.myapp.report")}, caller)
+	invocation = receiveInvocation(t, callee)
+	d.Cancel(&Cancel{Request: ID(12), Options: map[string]interface{}{"mode": KILL}}, caller)
+	r = <-callee.Receive()
+	if i, ok := r.(*Interrupt); !ok || i.Options["mode"] != KILL {
+		t.Error("Expected kill interrupt on callee ", r)
+	}
+	select {
+	case r := <-caller.Receive():
+		t.Error("Unexpected caller message before callee error ", r.MsgType())
+	case <-time.After(time.Millisecond * 50):
+	}
+	d.Error(&Error{Type: INVOCATION, Request: invocation, Error: URI("wamp.error.canceled"), Arguments: []interface{}{"partial"}}, callee)
+	r = <-caller.Receive()
+	if e, ok := r.(*Error); !ok || e.Type != CALL || e.Request != ID(12) || e.Error != URI("wamp.error.canceled") || e.Arguments[0] != "partial" {
+		t.Error("Expected canceled error on kill ", r)
+	}
+
+	// kill canceled call final result is reported as canceled
+	d.Call(&Call{Request: ID(13), Procedure: URI("com.myapp.report"), Options: map[string]interface{}{"receive_progress": true}}, caller)
+	invocation = receiveInvocation(t, callee)
+	d.Cancel(&Cancel{Request: ID(13), Options: map[string]interface{}{"mode": KILL}}, caller)
+	if r := <-callee.Receive(); r.MsgType() != INTERRUPT {
+		t.Error("Expected kill interrupt on callee ", r.MsgType())
+	}
+	d.Yield(&Yield{Request: invocation, Options: map[string]interface{}{"progress": true}, Arguments: []interface{}{"partial"}}, callee)
+	d.Yield(&Yield{Request: invocation, Options: map[string]interface{}{}, Arguments: []interface{}{"done"}}, callee)
+	r = <-caller.Receive()
+	if e, ok := r.(*Error); !ok || e.Request != ID(13) || e.Error != URI("wamp.error.canceled") {
+		t.Error("Expected canceled error on kill final result ", r)
+	}
+
+	d.mutex.RLock()
+	tasks := len(d.activeTasks)
+	d.mutex.RUnlock()
+	if tasks != 0 {
+		t.Error("Unexpected active tasks after cancel ", tasks)
+	}
+}
+
+func TestDealerKillCancelBeforeUnrequestedProgress(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+	callee := NewSession(NewFakePeer(PeerID("callee")))
+
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.report")}, callee)
+	if r := <-callee.Receive(); r.MsgType() != REGISTERED {
+		t.Fatal("Unexpected register response ", r.MsgType())
+	}
+
+	d.Call(&Call{Request: ID(2), Procedure: URI("com.myapp.report")}, caller)
+	invocation := receiveInvocation(t, callee)
+	d.Cancel(&Cancel{Request: ID(2), Options: map[string]interface{}{"mode": KILL}}, caller)
+	if r := <-callee.Receive(); r.MsgType() != INTERRUPT {
+		t.Error("Expected kill interrupt on callee ", r.MsgType())
+	}
+
+	d.Yield(&Yield{Request: invocation, Options: map[string]interface{}{"progress": true}}, callee)
+	d.Yield(&Yield{Request: invocation, Options: map[string]interface{}{}}, callee)
+	if e, ok := (<-caller.Receive()).(*Error); !ok || e.Request != ID(2) || e.Error != URI("wamp.error.canceled") {
+		t.Error("Expected canceled error ", e)
+	}
+
+	// tasks are interrupted once
+	task := newTask(caller, ID(3), URI("com.myapp.report"), false)
+	task.callee = callee
+	d.interrupt(task, KILL)
+	d.interrupt(task, KILLNOWAIT)
+	if r := <-callee.Receive(); r.MsgType() != INTERRUPT {
+		t.Error("Expected callee interrupt ", r.MsgType())
+	}
+	select {
+	case r := <-callee.Receive():
+		t.Error("Unexpected second callee interrupt ", r.MsgType())
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestDealerLeavingSessionTasks(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+	callee := NewSession(NewFakePeer(PeerID("callee")))
+
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.report")}, callee)
+	if r := <-callee.Receive(); r.MsgType() != REGISTERED {
+		t.Fatal("Unexpected register response ", r.MsgType())
+	}
+
+	// leaving caller interrupts its pending invocation
+	d.Call(&Call{Request: ID(2), Procedure: URI("com.myapp.report")}, caller)
+	invocation := receiveInvocation(t, callee)
+	d.UnregisterSession(caller)
+	r := <-callee.Receive()
+	if i, ok := r.(*Interrupt); !ok || i.Request != invocation {
+		t.Error("Expected interrupt on leaving caller ", r)
+	}
+
+	// leaving callee cancels pending call
+	d.Call(&Call{Request: ID(3), Procedure: URI("com.myapp.report")}, caller)
+	receiveInvocation(t, callee)
+	d.UnregisterSession(callee)
+	r = <-caller.Receive()
+	if e, ok := r.(*Error); !ok || e.Request != ID(3) || e.Error != URI("wamp.error.canceled") {
+		t.Error("Expected canceled error on leaving callee ", r)
+	}
+
+	d.mutex.RLock()
+	tasks, calls := len(d.activeTasks), len(d.calls)
+	d.mutex.RUnlock()
+	if tasks != 0 || calls != 0 {
+		t.Error("Unexpected pending tasks after sessions left ", tasks, calls)
+	}
+}
+
+func TestDealerProgressiveCallResults(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+	callee := NewSession(NewFakePeer(PeerID("callee")))
+
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.report")}, callee)
+	if r := <-callee.Receive(); r.MsgType() != REGISTERED {
+		t.Fatal("Unexpected register response ", r.MsgType())
+	}
+
+	d.Call(&Call{Request: ID(2), Procedure: URI("com.myapp.report"), Options: map[string]interface{}{"receive_progress": true}}, caller)
+	r := <-callee.Receive()
+	inv, ok := r.(*Invocation)
+	if !ok || inv.Details["receive_progress"] != true {
+		t.Fatal("Expected receive_progress on invocation ", r)
+	}
+
+	for i := 0; i < 3; i++ {
+		d.Yield(&Yield{Request: inv.Request, Options: map[string]interface{}{"progress": true}, Arguments: []interface{}{i}}, callee)
+		r := <-caller.Receive()
+		res, ok := r.(*Result)
+		if !ok || res.Details["progress"] != true || res.Arguments[0] != i {
+			t.Error("Unexpected progressive result ", r)
+		}
+	}
+	d.Yield(&Yield{Request: inv.Request, Options: map[string]interface{}{}, Arguments: []interface{}{"done"}}, callee)
+	r = <-caller.Receive()
+	if res, ok := r.(*Result); !ok || res.Details["progress"] != nil || res.Arguments[0] != "done" {
+		t.Error("Unexpected final result ", r)
+	}
+	if len(d.activeTasks) != 0 {
+		t.Error("Unexpected active tasks after final result")
+	}
+
+	// progressive results on calls without receive_progress fail
+	d.Call(&Call{Request: ID(3), Procedure: URI("com.myapp.report")}, caller)
+	invocation := receiveInvocation(t, callee)
+	d.Yield(&Yield{Request: invocation, Options: map[string]interface{}{"progress": true}}, callee)
+	r = <-caller.Receive()
+	if e, ok := r.(*Error); !ok || e.Type != CALL || e.Request != ID(3) {
+		t.Error("Expected error on not requested progressive result ", r)
+	}
+	if r := <-callee.Receive(); r.MsgType() != INTERRUPT {
+		t.Error("Expected callee interrupt ", r.MsgType())
+	}
+}
+
+func TestDealerCalleeErrorPropagation(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+	callee := NewSession(NewFakePeer(PeerID("callee")))
+
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.divide")}, callee)
+	if r := <-callee.Receive(); r.MsgType() != REGISTERED {
+		t.Fatal("Unexpected register response ", r.MsgType())
+	}
+
+	d.Call(&Call{Request: ID(2), Procedure: URI("com.myapp.divide"), Arguments: []interface{}{1, 0}}, caller)
+	invocation := receiveInvocation(t, callee)
+
+	// errors from other sessions are discarded
+	d.Error(&Error{Type: INVOCATION, Request: invocation, Error: URI("com.myapp.error.fake")}, caller)
+	d.Error(&Error{
+		Type:        INVOCATION,
+		Request:     invocation,
+		Details:     map[string]interface{}{},
+		Error:       URI("com.myapp.error.division_by_zero"),
+		Arguments:   []interface{}{"division by zero"},
+		ArgumentsKw: map[string]interface{}{"dividend": 1},
+	}, callee)
+
+	r := <-caller.Receive()
+	e, ok := r.(*Error)
+	if !ok {
+		t.Fatal("Expected error on caller ", r.MsgType())
+	}
+	if e.Type != CALL || e.Request != ID(2) || e.Error != URI("com.myapp.error.division_by_zero") {
+		t.Error("Unexpected caller error ", e)
+	}
+	if e.Arguments[0] != "division by zero" || e.ArgumentsKw["dividend"] != 1 {
+		t.Error("Unexpected caller error payload ", e)
+	}
+	if len(d.activeTasks) != 0 {
+		t.Error("Unexpected active tasks after callee error")
+	}
+}
+
+func TestDealerInternalHandlerError(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+	si := NewSession(NewInternalPeer())
+	done := make(chan struct{})
+	defer close(done)
+
+	// route internal session errors as router session handler does
+	go func() {
+		for {
+			select {
+			case msg := <-si.Receive():
+				go d.Error(msg, si)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	uri := URI("com.myapp.fail")
+	si.register(uri, func(msg Message) (Message, error) {
+		return nil, fmt.Errorf("handler failed")
+	})
+	d.Register(&Register{Request: ID(1), Procedure: uri}, si)
+
+	d.Call(&Call{Request: ID(2), Procedure: uri}, caller)
+	r := <-caller.Receive()
+	e, ok := r.(*Error)
+	if !ok || e.Type != CALL || e.Request != ID(2) || e.Error != URI("wamp.error.runtime_error") || e.Arguments[0] != "handler failed" {
+		t.Error("Unexpected handler error on caller ", r)
+	}
+}
+
+func TestDealerInvocationRequestCorrelation(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	callerA := NewSession(NewFakePeer(PeerID("callerA")))
+	callerB := NewSession(NewFakePeer(PeerID("callerB")))
+	callee := NewSession(NewFakePeer(PeerID("callee")))
+
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.echo")}, callee)
+	if r := <-callee.Receive(); r.MsgType() != REGISTERED {
+		t.Fatal("Unexpected register response ", r.MsgType())
+	}
+
+	// both callers use the same call request id
+	d.Call(&Call{Request: ID(7), Procedure: URI("com.myapp.echo"), Arguments: []interface{}{"A"}}, callerA)
+	invocationA := receiveInvocation(t, callee)
+	d.Call(&Call{Request: ID(7), Procedure: URI("com.myapp.echo"), Arguments: []interface{}{"B"}}, callerB)
+	invocationB := receiveInvocation(t, callee)
+	if invocationA == invocationB {
+		t.Fatal("Expected router allocated invocation ids")
+	}
+
+	d.Yield(&Yield{Request: invocationB, Arguments: []interface{}{"B"}}, callee)
+	d.Yield(&Yield{Request: invocationA, Arguments: []interface{}{"A"}}, callee)
+	for caller, arg := range map[*Session]string{callerA: "A", callerB: "B"} {
+		r := <-caller.Receive()
+		res, ok := r.(*Result)
+		if !ok || res.Request != ID(7) || res.Arguments[0] != arg {
+			t.Error("Unexpected result on caller ", caller.ID(), r)
+		}
+	}
+}
+
+func TestIdScopes(t *testing.T) {
+	g := newIdGenerator()
+	if g.next() != ID(1) || g.next() != ID(2) {
+		t.Error("Unexpected sequential ids")
+	}
+	g.last = maxId
+	if g.next() != ID(1) {
+		t.Error("Expected id wrap around")
+	}
+
+	for i := 0; i < 1000; i++ {
+		if id := NewId(); id < 1 || id > maxId {
+			t.Fatal("Global id out of range ", id)
+		}
+	}
+}
+
+func receiveInvocation(t *testing.T, s *Session) ID {
+	r := <-s.Receive()
+	inv, ok := r.(*Invocation)
+	if !ok {
+		t.Fatal("Expected invocation ", r.MsgType())
+	}
+
+	return inv.Request
+}
+
+func TestDealerCallerDisclosure(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	d.disclosure = DISCLOSE_ALWAYS
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+	caller.sessionID = ID(42)
+	caller.identity = &Identity{AuthID: "joe", AuthRole: "frontend"}
+	callee := NewSession(NewFakePeer(PeerID("callee")))
+
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.echo")}, callee)
+	<-callee.Receive()
+
+	// realm policy forces disclosure
+	d.Call(&Call{Request: ID(2), Procedure: URI("com.myapp.echo")}, caller)
+	r := <-callee.Receive()
+	inv, ok := r.(*Invocation)
+	if !ok || inv.Details["caller"] != ID(42) || inv.Details["caller_authid"] != "joe" || inv.Details["caller_authrole"] != "frontend" {
+		t.Error("Unexpected caller disclosure ", r)
+	}
+
+	// caller private options are not forwarded
+	d.Call(&Call{Request: ID(3), Procedure: URI("com.myapp.echo"), Options: map[string]interface{}{"disclose_me": true, "timeout": 1000}}, caller)
+	inv = (<-callee.Receive()).(*Invocation)
+	if _, ok := inv.Details["disclose_me"]; ok || inv.Details["timeout"] != 1000 {
+		t.Error("Unexpected invocation details ", inv.Details)
+	}
+}
+
+func TestDealerRegistrationMetaApi(t *testing.T) {
+	m := &recordingMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+
+	workers := []*Session{}
+	for i := 1; i <= 2; i++ {
+		w := NewSession(NewFakePeer(PeerID(fmt.Sprintf("worker%d", i))))
+		w.sessionID = ID(i)
+		d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.job"), Options: map[string]interface{}{"invoke": ROUNDROBIN}}, w)
+		<-w.Receive()
+		workers = append(workers, w)
+	}
+	p := NewSession(NewFakePeer(PeerID("pattern")))
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp."), Options: map[string]interface{}{"match": PREFIX}}, p)
+	<-p.Receive()
+
+	call := func(h Handler, args ...interface{}) (*Yield, error) {
+		r, err := h(&Invocation{Request: ID(1), Arguments: args})
+		if err != nil {
+			return nil, err
+		}
+		return r.(*Yield), nil
+	}
+
+	y, _ := call(d.metaLookup, "com.myapp.job")
+	id, ok := y.Arguments[0].(ID)
+	if !ok {
+		t.Fatal("Unexpected registration lookup ", y.Arguments)
+	}
+	y, _ = call(d.metaList)
+	list := y.Arguments[0].(map[string]interface{})
+	if len(list[EXACT].([]interface{})) != 1 || len(list[PREFIX].([]interface{})) != 1 {
+		t.Error("Unexpected registration list ", list)
+	}
+	y, _ = call(d.metaMatch, "com.myapp.other")
+	if y.Arguments[0] == id || y.Arguments[0] == nil {
+		t.Error("Unexpected prefix registration match ", y.Arguments)
+	}
+	y, _ = call(d.metaGet, float64(id))
+	details := y.Arguments[0].(map[string]interface{})
+	if details["uri"] != URI("com.myapp.job") || details["match"] != EXACT || details["invoke"] != ROUNDROBIN || details["created"] == "" {
+		t.Error("Unexpected registration details ", details)
+	}
+	y, _ = call(d.metaListCallees, float64(id))
+	if callees := y.Arguments[0].([]interface{}); len(callees) != 2 || callees[0] != ID(1) || callees[1] != ID(2) {
+		t.Error("Unexpected callees ", callees)
+	}
+	if _, err := call(d.metaCountCallees, float64(12345)); err == nil || err.(*handlerError).uri != URI("wamp.error.no_such_registration") {
+		t.Error("Expected no such registration error ", err)
+	}
+
+	// leaving workers are unregistered, void registration is deleted
+	for _, w := range workers {
+		d.UnregisterSession(w)
+	}
+	if _, err := call(d.metaGet, float64(id)); err == nil {
+		t.Error("Expected deleted registration")
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.events[Topic("wamp.registration.on_create")]) != 2 || len(m.events[Topic("wamp.registration.on_register")]) != 3 ||
+		len(m.events[Topic("wamp.registration.on_unregister")]) != 2 || len(m.events[Topic("wamp.registration.on_delete")]) != 1 {
+		t.Error("Unexpected registration meta events ", m.events)
+	}
+	if deleted := m.events[Topic("wamp.registration.on_delete")][0]; deleted[0] != ID(2) || deleted[1] != id {
+		t.Error("Unexpected on_delete payload ", deleted)
+	}
+}
+
+func TestDealerRevokeRegistration(t *testing.T) {
+	m := &fakeSessionMetaEventsHandler{}
+	d := NewDealer(m, NewRuleAuthorizer(DefaultRules()))
+	worker := NewSession(NewFakePeer(PeerID("rogue")))
+	worker.sessionID = ID(9)
+	d.Register(&Register{Request: ID(1), Procedure: URI("com.myapp.payments")}, worker)
+	id := (<-worker.Receive()).(*Registered).Registration
+
+	r, err := d.revokeRegistration(&Invocation{Request: ID(1), Arguments: []interface{}{float64(id)}, ArgumentsKw: map[string]interface{}{"reason": "com.myapp.rogue_worker"}})
+	if err != nil || r.(*Yield).Arguments[0].([]interface{})[0] != ID(9) {
+		t.Fatal("Unexpected revoked callees ", r, err)
+	}
+	u, ok := (<-worker.Receive()).(*Unregistered)
+	if !ok || u.Details["registration"] != id || u.Details["reason"] != URI("com.myapp.rogue_worker") {
+		t.Error("Unexpected revocation ", u)
+	}
+	if len(worker.getRegistrations()) != 0 || len(d.registrations) != 0 || len(d.sessionHandlers) != 0 {
+		t.Error("Unexpected registrations after revocation")
+	}
+
+	caller := NewSession(NewFakePeer(PeerID("caller")))
+	d.Call(&Call{Request: ID(2), Procedure: URI("com.myapp.payments")}, caller)
+	if e, ok := (<-caller.Receive()).(*Error); !ok || e.Error != URI("wamp.error.no_such_procedure") {
+		t.Error("Expected no such procedure after revocation ", e)
+	}
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"fmt"
+)
+
+// Realm disclosure policies on caller and publisher identification,
+// optional honours disclose_me option
+const (
+	DISCLOSE_OPTIONAL = "optional"
+	DISCLOSE_ALWAYS   = "always"
+	DISCLOSE_NEVER    = "never"
+)
+
+func validDisclosurePolicy(policy string) bool {
+	return policy == "" || policy == DISCLOSE_OPTIONAL || policy == DISCLOSE_ALWAYS || policy == DISCLOSE_NEVER
+}
+
+// discloseIdentity decides caller or publisher disclosure from disclose_me
+// option and realm policy, error if policy forbids requested disclosure
+func discloseIdentity(policy string, options map[string]interface{}) (bool, error) {
+	discloseMe, _ := options["disclose_me"].(bool)
+	switch policy {
+	case DISCLOSE_ALWAYS:
+		return true, nil
+	case DISCLOSE_NEVER:
+		if discloseMe {
+			return false, fmt.Errorf("Disclosure forbidden by realm policy")
+		}
+		return false, nil
+	default:
+		return discloseMe, nil
+	}
+}
+
+func disclosureDisallowed(t MsgType, request ID, err error) *Error {
+	return &Error{
+		Type:      t,
+		Request:   request,
+		Details:   map[string]interface{}{},
+		Error:     URI("wamp.error.option_disallowed.disclose_me"),
+		Arguments: []interface{}{err.Error()},
+	}
+}
@@ -0,0 +1,427 @@
+package core
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSegmentSize = 4 << 20
+	defaultMaxSegments = 16
+	indexEntrySize     = 32
+)
+
+// indexEntry locates a stored event record on segment log
+type indexEntry struct {
+	publication ID
+	timestamp   int64
+	offset      int64
+	length      int64
+}
+
+// segment is an append only events log with its fixed size entries
+// index, files are named by first event sequence
+type segment struct {
+	base    uint64
+	log     *os.File
+	index   *os.File
+	size    int64
+	entries []indexEntry
+}
+
+// fileEventStore persists events on append only log segments, rotated
+// on segmentSize, oldest segments are removed over maxSegments, topics
+// keeps each topic stored sequences so queries only read matching records,
+// sequences over retention policy limit are dropped from it
+type fileEventStore struct {
+	dir          string
+	segmentSize  int64
+	maxSegments  int
+	policies     []RetentionPolicy
+	segments     []*segment
+	publications map[ID]uint64
+	topics       map[Topic][]uint64
+	closed       bool
+	mutex        *sync.RWMutex
+}
+
+var errEventStoreClosed = fmt.Errorf("Event store closed")
+
+// NewFileEventStore opens dir event store, recovering existing segments,
+// void segmentSize or maxSegments take defaults
+func NewFileEventStore(dir string, segmentSize int64, maxSegments int, policies []RetentionPolicy) (EventStore, error) {
+	if segmentSize <= 0 {
+		segmentSize = defaultSegmentSize
+	}
+	if maxSegments <= 0 {
+		maxSegments = defaultMaxSegments
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	f := &fileEventStore{
+		dir:          dir,
+		segmentSize:  segmentSize,
+		maxSegments:  maxSegments,
+		policies:     policies,
+		publications: make(map[ID]uint64),
+		topics:       make(map[Topic][]uint64),
+		mutex:        &sync.RWMutex{},
+	}
+	if err := f.open(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// open loads segments on sequence order, building topics index
+// from stored records
+func (f *fileEventStore) open() error {
+	files, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		return err
+	}
+
+	bases := []uint64{}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, ".log"), 10, 64)
+		if err != nil {
+			log.Println("Skipping unexpected event store file ", name)
+			continue
+		}
+		bases = append(bases, base)
+	}
+	sort.Sort(byBase(bases))
+
+	for _, base := range bases {
+		s, err := f.openSegment(base)
+		if err != nil {
+			return err
+		}
+		f.segments = append(f.segments, s)
+		for i, e := range s.entries {
+			topic, err := s.topic(e)
+			if err != nil {
+				return err
+			}
+			f.publications[e.publication] = s.base + uint64(i)
+			f.index(topic, s.base+uint64(i))
+		}
+	}
+
+	return nil
+}
+
+// openSegment loads segment index, records written without index
+// entry on an interrupted append are truncated
+func (f *fileEventStore) openSegment(base uint64) (*segment, error) {
+	name := filepath.Join(f.dir, fmt.Sprintf("%020d", base))
+	logFile, err := os.OpenFile(name+".log", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	indexFile, err := os.OpenFile(name+".idx", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	s := &segment{base: base, log: logFile, index: indexFile}
+
+	info, err := logFile.Stat()
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(indexFile)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	for i := 0; i+indexEntrySize <= len(data); i += indexEntrySize {
+		e := decodeIndexEntry(data[i : i+indexEntrySize])
+		if e.offset != s.size || e.offset+e.length > info.Size() {
+			break
+		}
+		s.entries = append(s.entries, e)
+		s.size += e.length
+	}
+
+	if err := indexFile.Truncate(int64(len(s.entries) * indexEntrySize)); err != nil {
+		s.close()
+		return nil, err
+	}
+	if err := logFile.Truncate(s.size); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// next returns sequence assigned to next stored event
+func (f *fileEventStore) next() uint64 {
+	if len(f.segments) == 0 {
+		return 1
+	}
+	last := f.segments[len(f.segments)-1]
+
+	return last.base + uint64(len(last.entries))
+}
+
+// rotate opens a new segment removing oldest ones over maxSegments
+func (f *fileEventStore) rotate() (*segment, error) {
+	s, err := f.openSegment(f.next())
+	if err != nil {
+		return nil, err
+	}
+	f.segments = append(f.segments, s)
+
+	for len(f.segments) > f.maxSegments {
+		oldest := f.segments[0]
+		f.segments = f.segments[1:]
+		for _, e := range oldest.entries {
+			delete(f.publications, e.publication)
+		}
+		oldest.close()
+		os.Remove(oldest.log.Name())
+		os.Remove(oldest.index.Name())
+	}
+	f.trimTopics(f.segments[0].base)
+
+	return s, nil
+}
+
+// Store appends event record to current segment log and then its index entry
+func (f *fileEventStore) Store(e *StoredEvent) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return errEventStoreClosed
+	}
+	e.Sequence = f.next()
+	record, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	record = append(record, '\n')
+
+	var s *segment
+	if len(f.segments) > 0 {
+		s = f.segments[len(f.segments)-1]
+	}
+	if s == nil || len(s.entries) > 0 && s.size+int64(len(record)) > f.segmentSize {
+		if s, err = f.rotate(); err != nil {
+			return err
+		}
+	}
+
+	entry := indexEntry{
+		publication: e.Publication,
+		timestamp:   e.Timestamp.UnixNano(),
+		offset:      s.size,
+		length:      int64(len(record)),
+	}
+	if _, err := s.log.WriteAt(record, entry.offset); err != nil {
+		return err
+	}
+	if _, err := s.index.WriteAt(encodeIndexEntry(entry), int64(len(s.entries)*indexEntrySize)); err != nil {
+		return err
+	}
+	s.entries = append(s.entries, entry)
+	s.size += entry.length
+	f.publications[e.Publication] = e.Sequence
+	f.index(e.Topic, e.Sequence)
+
+	return nil
+}
+
+// index appends topic sequence dropping the ones over retention limit
+func (f *fileEventStore) index(topic Topic, seq uint64) {
+	seqs := append(f.topics[topic], seq)
+	if p := retentionPolicy(f.policies, topic); p != nil && p.Limit > 0 {
+		for len(seqs) > p.Limit {
+			s, i := f.locate(seqs[0])
+			delete(f.publications, s.entries[i].publication)
+			seqs = seqs[1:]
+		}
+	}
+	f.topics[topic] = seqs
+}
+
+// trimTopics drops indexed sequences older than first
+func (f *fileEventStore) trimTopics(first uint64) {
+	for topic, seqs := range f.topics {
+		i := sort.Search(len(seqs), func(i int) bool { return seqs[i] >= first })
+		if i == len(seqs) {
+			delete(f.topics, topic)
+			continue
+		}
+		f.topics[topic] = seqs[i:]
+	}
+}
+
+// locate finds segment and entry position of sequence
+func (f *fileEventStore) locate(seq uint64) (*segment, int) {
+	j := sort.Search(len(f.segments), func(j int) bool { return f.segments[j].base > seq }) - 1
+
+	return f.segments[j], int(seq - f.segments[j].base)
+}
+
+// Events collects matching topics sequences on query bounds, reading
+// their records on paging direction and query time range
+func (f *fileEventStore) Events(q *EventQuery) ([]*StoredEvent, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	if f.closed {
+		return nil, errEventStoreClosed
+	}
+	lo, hi, err := q.bounds(func(id ID) (uint64, bool) {
+		seq, ok := f.publications[id]
+		return seq, ok
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seqs := []uint64{}
+	for topic, stored := range f.topics {
+		if !q.matches(topic) {
+			continue
+		}
+		for _, seq := range stored {
+			if seq >= lo && seq <= hi {
+				seqs = append(seqs, seq)
+			}
+		}
+	}
+	if q.ascending() {
+		sort.Sort(byBase(seqs))
+	} else {
+		sort.Sort(sort.Reverse(byBase(seqs)))
+	}
+
+	events := []*StoredEvent{}
+	for _, seq := range seqs {
+		s, i := f.locate(seq)
+		if !q.inTime(time.Unix(0, s.entries[i].timestamp)) {
+			continue
+		}
+		e, err := s.read(s.entries[i])
+		if err != nil {
+			return events, err
+		}
+		events = append(events, e)
+		if q.Limit > 0 && len(events) >= q.Limit {
+			break
+		}
+	}
+	if q.ascending() {
+		reverse(events)
+	}
+
+	return events, nil
+}
+
+// Close closes segments, stored and queried events fail afterwards
+func (f *fileEventStore) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	var err error
+	for _, s := range f.segments {
+		if e := s.close(); e != nil {
+			err = e
+		}
+	}
+	f.segments = nil
+
+	return err
+}
+
+func (s *segment) read(e indexEntry) (*StoredEvent, error) {
+	record := make([]byte, e.length)
+	if _, err := s.log.ReadAt(record, e.offset); err != nil {
+		return nil, err
+	}
+	event := &StoredEvent{}
+	if err := json.Unmarshal(record, event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// topic decodes record topic only
+func (s *segment) topic(e indexEntry) (Topic, error) {
+	record := make([]byte, e.length)
+	if _, err := s.log.ReadAt(record, e.offset); err != nil {
+		return "", err
+	}
+	event := &struct {
+		Topic Topic `json:"topic"`
+	}{}
+	if err := json.Unmarshal(record, event); err != nil {
+		return "", err
+	}
+
+	return event.Topic, nil
+}
+
+func (s *segment) close() error {
+	s.log.Sync()
+	s.index.Sync()
+	err := s.log.Close()
+	if e := s.index.Close(); e != nil {
+		err = e
+	}
+
+	return err
+}
+
+func encodeIndexEntry(e indexEntry) []byte {
+	b := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint64(b[0:], uint64(e.publication))
+	binary.BigEndian.PutUint64(b[8:], uint64(e.timestamp))
+	binary.BigEndian.PutUint64(b[16:], uint64(e.offset))
+	binary.BigEndian.PutUint64(b[24:], uint64(e.length))
+
+	return b
+}
+
+func decodeIndexEntry(b []byte) indexEntry {
+	return indexEntry{
+		publication: ID(binary.BigEndian.Uint64(b[0:])),
+		timestamp:   int64(binary.BigEndian.Uint64(b[8:])),
+		offset:      int64(binary.BigEndian.Uint64(b[16:])),
+		length:      int64(binary.BigEndian.Uint64(b[24:])),
+	}
+}
+
+type byBase []uint64
+
+func (b byBase) Len() int           { return len(b) }
+func (b byBase) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byBase) Less(i, j int) bool { return b[i] < b[j] }
@@ -0,0 +1,215 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileEventStorePaging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileEventStore(dir, 256, 100, nil)
+	if err != nil {
+		t.Fatal("Unexpected error opening file event store ", err)
+	}
+	defer store.Close()
+
+	testEventStorePaging(t, store)
+	if len(store.(*fileEventStore).segments) < 2 {
+		t.Error("Expected rotated segments")
+	}
+}
+
+func TestFileEventStoreRecovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileEventStore(dir, 256, 3, nil)
+	if err != nil {
+		t.Fatal("Unexpected error opening file event store ", err)
+	}
+	for i := 1; i <= 20; i++ {
+		e := &StoredEvent{Publication: ID(i), Timestamp: time.Now(), Topic: Topic("com.myapp.state"), Arguments: []interface{}{i}}
+		if err := store.Store(e); err != nil {
+			t.Fatal("Unexpected error storing event ", err)
+		}
+	}
+	events, _ := store.Events(&EventQuery{Topic: Topic("com.myapp.state")})
+	store.Close()
+
+	logs, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	if len(logs) != 3 || len(events) == 0 || len(events) == 20 {
+		t.Fatal("Unexpected rotated segments ", len(logs), " with events ", len(events))
+	}
+
+	// interrupted append, record written without index entry
+	f, err := os.OpenFile(logs[len(logs)-1], os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte(`{"sequence":999,"publication":999,"topic":"com.myapp.state"}` + "\n"))
+	f.Close()
+
+	store, err = NewFileEventStore(dir, 256, 3, nil)
+	if err != nil {
+		t.Fatal("Unexpected error reopening file event store ", err)
+	}
+	defer store.Close()
+
+	recovered, err := store.Events(&EventQuery{Topic: Topic("com.myapp.state")})
+	if err != nil || len(recovered) != len(events) {
+		t.Fatal("Unexpected recovered events ", len(recovered), err)
+	}
+	for i := range events {
+		if recovered[i].Publication != events[i].Publication || recovered[i].Sequence != events[i].Sequence {
+			t.Error("Unexpected recovered event ", recovered[i], " expected ", events[i])
+		}
+	}
+	if recovered[0].Publication != ID(20) || recovered[0].Arguments[0] != float64(20) {
+		t.Error("Unexpected last recovered event ", recovered[0])
+	}
+
+	e := &StoredEvent{Publication: ID(21), Timestamp: time.Now(), Topic: Topic("com.myapp.state")}
+	if err := store.Store(e); err != nil || e.Sequence != recovered[0].Sequence+1 {
+		t.Error("Unexpected sequence after recovery ", e.Sequence, err)
+	}
+	page, err := store.Events(&EventQuery{Topic: Topic("com.myapp.state"), After: ID(20)})
+	if err != nil || len(page) != 1 || page[0].Publication != ID(21) {
+		t.Error("Unexpected events after recovered publication ", page, err)
+	}
+}
+
+func TestFileEventStoreClosed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileEventStore(dir, 256, 3, nil)
+	if err != nil {
+		t.Fatal("Unexpected error opening file event store ", err)
+	}
+	store.Store(&StoredEvent{Publication: ID(1), Timestamp: time.Now(), Topic: Topic("com.myapp.state")})
+	if err := store.Close(); err != nil {
+		t.Fatal("Unexpected error closing file event store ", err)
+	}
+
+	// publications racing realm termination are not stored
+	if err := store.Store(&StoredEvent{Publication: ID(2), Timestamp: time.Now(), Topic: Topic("com.myapp.state")}); err == nil {
+		t.Error("Expected error storing on closed event store")
+	}
+	if _, err := store.Events(&EventQuery{Topic: Topic("com.myapp.state")}); err == nil {
+		t.Error("Expected error querying closed event store")
+	}
+	if err := store.Close(); err != nil {
+		t.Error("Unexpected error closing twice ", err)
+	}
+
+	store, err = NewFileEventStore(dir, 256, 3, nil)
+	if err != nil {
+		t.Fatal("Unexpected error reopening file event store ", err)
+	}
+	defer store.Close()
+	events, err := store.Events(&EventQuery{Topic: Topic("com.myapp.state")})
+	if err != nil || len(events) != 1 || events[0].Publication != ID(1) || events[0].Sequence != 1 {
+		t.Error("Unexpected events after close ", events, err)
+	}
+}
+
+func TestFileEventStoreTopicIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewFileEventStore(dir, 256, 3, nil)
+	if err != nil {
+		t.Fatal("Unexpected error opening file event store ", err)
+	}
+	defer store.Close()
+	for i := 1; i <= 20; i++ {
+		topic := Topic("com.myapp.state")
+		if i%2 == 0 {
+			topic = Topic("com.myapp.other")
+		}
+		if err := store.Store(&StoredEvent{Publication: ID(i), Timestamp: time.Now(), Topic: topic}); err != nil {
+			t.Fatal("Unexpected error storing event ", err)
+		}
+	}
+
+	// other topic records are not read on state topic queries
+	f := store.(*fileEventStore)
+	for _, s := range f.segments {
+		for i, e := range s.entries {
+			if e.publication%2 == 0 {
+				s.log.WriteAt([]byte("#"), s.entries[i].offset)
+			}
+		}
+	}
+	first := f.segments[0].base
+	if seqs := f.topics[Topic("com.myapp.state")]; len(seqs) == 0 || seqs[0] < first {
+		t.Error("Unexpected indexed sequences of removed segments ", seqs, first)
+	}
+
+	events, err := store.Events(&EventQuery{Topic: Topic("com.myapp.state"), Limit: 3})
+	if err != nil || len(events) != 3 {
+		t.Fatal("Unexpected topic events ", events, err)
+	}
+	for i, e := range events {
+		if e.Publication != ID(19-2*i) {
+			t.Error("Unexpected topic event ", e.Publication)
+		}
+	}
+	if _, err := store.Events(&EventQuery{Topic: Topic("com.myapp.other")}); err == nil {
+		t.Error("Expected error reading corrupted records")
+	}
+}
+
+func TestFileEventStoreRetentionLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	policies := []RetentionPolicy{{URI: URI("com.myapp.state"), Limit: 2}}
+	store, err := NewFileEventStore(dir, 1024, 3, policies)
+	if err != nil {
+		t.Fatal("Unexpected error opening file event store ", err)
+	}
+	for i := 1; i <= 5; i++ {
+		if err := store.Store(&StoredEvent{Publication: ID(i), Timestamp: time.Now(), Topic: Topic("com.myapp.state")}); err != nil {
+			t.Fatal("Unexpected error storing event ", err)
+		}
+	}
+	events, err := store.Events(&EventQuery{Topic: Topic("com.myapp.state")})
+	if err != nil || len(events) != 2 || events[0].Publication != ID(5) || events[1].Publication != ID(4) {
+		t.Error("Unexpected events over retention limit ", events, err)
+	}
+	if _, err := store.Events(&EventQuery{Topic: Topic("com.myapp.state"), After: ID(2)}); err == nil {
+		t.Error("Expected error paging after dropped publication")
+	}
+	store.Close()
+
+	store, err = NewFileEventStore(dir, 1024, 3, policies)
+	if err != nil {
+		t.Fatal("Unexpected error reopening file event store ", err)
+	}
+	defer store.Close()
+	events, err = store.Events(&EventQuery{Topic: Topic("com.myapp.state")})
+	if err != nil || len(events) != 2 || events[0].Publication != ID(5) {
+		t.Error("Unexpected recovered events over retention limit ", events, err)
+	}
+}
@@ -0,0 +1,267 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RetentionPolicy keeps last Limit events published on topics matching
+// URI, MaxAge in seconds discards older events, void values are unlimited,
+// file event stores retain events up to its segments
+type RetentionPolicy struct {
+	URI    URI    `json:"uri"`
+	Match  string `json:"match"`
+	Limit  int    `json:"limit"`
+	MaxAge int    `json:"max_age"`
+}
+
+func (p *RetentionPolicy) expired(e *StoredEvent, now time.Time) bool {
+	return p.MaxAge > 0 && now.Sub(e.Timestamp) > time.Duration(p.MaxAge)*time.Second
+}
+
+// retentionPolicy returns first policy matching topic
+func retentionPolicy(policies []RetentionPolicy, topic Topic) *RetentionPolicy {
+	for i := range policies {
+		if matchURI(policies[i].Match, string(policies[i].URI), string(topic)) {
+			return &policies[i]
+		}
+	}
+
+	return nil
+}
+
+// StoredEvent is a publication persisted on event store, Sequence
+// is assigned by the store on publication order
+type StoredEvent struct {
+	Sequence    uint64                 `json:"sequence"`
+	Publication ID                     `json:"publication"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Topic       Topic                  `json:"topic"`
+	Arguments   []interface{}          `json:"args,omitempty"`
+	ArgumentsKw map[string]interface{} `json:"kwargs,omitempty"`
+}
+
+// EventQuery selects stored events on topics matching Topic, Before and
+// After page by publication ids, Since and Until by timestamp, void
+// values are unbounded
+type EventQuery struct {
+	Match  string
+	Topic  Topic
+	Limit  int
+	Before ID
+	After  ID
+	Since  time.Time
+	Until  time.Time
+}
+
+// bounds resolves publication paging to sequence range
+func (q *EventQuery) bounds(sequence func(ID) (uint64, bool)) (uint64, uint64, error) {
+	lo, hi := uint64(0), ^uint64(0)
+	if q.After != 0 {
+		seq, ok := sequence(q.After)
+		if !ok {
+			return 0, 0, newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Publication %d not found on event store", q.After))
+		}
+		lo = seq + 1
+	}
+	if q.Before != 0 {
+		seq, ok := sequence(q.Before)
+		if !ok {
+			return 0, 0, newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Publication %d not found on event store", q.Before))
+		}
+		hi = seq - 1
+	}
+
+	return lo, hi, nil
+}
+
+func (q *EventQuery) inTime(t time.Time) bool {
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+
+	return q.Until.IsZero() || !t.After(q.Until)
+}
+
+func (q *EventQuery) matches(topic Topic) bool {
+	return matchURI(q.Match, string(q.Topic), string(topic))
+}
+
+// ascending pages forward from After publication, otherwise
+// pages backwards from most recent or Before publication
+func (q *EventQuery) ascending() bool {
+	return q.After != 0 && q.Before == 0
+}
+
+// EventStore persists publications, Events returns query
+// results most recent first
+type EventStore interface {
+	Store(e *StoredEvent) error
+	Events(q *EventQuery) ([]*StoredEvent, error)
+	Close() error
+}
+
+// eventHistory stores events on topics with retention policy,
+// first matching retention policy applies
+type eventHistory struct {
+	policies []RetentionPolicy
+	events   EventStore
+}
+
+func newEventHistory(policies []RetentionPolicy, events EventStore) *eventHistory {
+	return &eventHistory{
+		policies: policies,
+		events:   events,
+	}
+}
+
+// store persists event if topic has retention policy
+func (h *eventHistory) store(e *StoredEvent) {
+	if retentionPolicy(h.policies, e.Topic) == nil {
+		return
+	}
+
+	if err := h.events.Store(e); err != nil {
+		log.Println("Error storing publication ", e.Publication, err)
+	}
+}
+
+// retained returns last event on each topic matching subscription
+func (h *eventHistory) retained(match string, topic Topic) []*StoredEvent {
+	q := &EventQuery{Match: match, Topic: topic}
+	if match == EXACT || match == "" {
+		q.Limit = 1
+	}
+	events, err := h.history(q)
+	if err != nil {
+		log.Println("Error getting retained events ", err)
+		return nil
+	}
+
+	topics := make(map[Topic]bool)
+	retained := []*StoredEvent{}
+	for _, e := range events {
+		if topics[e.Topic] {
+			continue
+		}
+		topics[e.Topic] = true
+		retained = append(retained, e)
+	}
+
+	return retained
+}
+
+// history returns not expired events matching query, most recent first
+func (h *eventHistory) history(q *EventQuery) ([]*StoredEvent, error) {
+	events, err := h.events.Events(q)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	history := []*StoredEvent{}
+	for _, e := range events {
+		if p := retentionPolicy(h.policies, e.Topic); p != nil && p.expired(e, now) {
+			continue
+		}
+		history = append(history, e)
+	}
+
+	return history, nil
+}
+
+// memoryEventStore retains events in memory by topic, trimmed
+// by retention policies
+type memoryEventStore struct {
+	policies     []RetentionPolicy
+	events       map[Topic][]*StoredEvent
+	publications map[ID]uint64
+	sequence     uint64
+	mutex        *sync.RWMutex
+}
+
+func NewMemoryEventStore(policies []RetentionPolicy) EventStore {
+	return &memoryEventStore{
+		policies:     policies,
+		events:       make(map[Topic][]*StoredEvent),
+		publications: make(map[ID]uint64),
+		mutex:        &sync.RWMutex{},
+	}
+}
+
+// Store appends event trimming expired and over limit topic events
+func (m *memoryEventStore) Store(e *StoredEvent) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sequence++
+	e.Sequence = m.sequence
+	m.publications[e.Publication] = e.Sequence
+
+	events := append(m.events[e.Topic], e)
+	if p := retentionPolicy(m.policies, e.Topic); p != nil {
+		for len(events) > 0 && (p.Limit > 0 && len(events) > p.Limit || p.expired(events[0], e.Timestamp)) {
+			delete(m.publications, events[0].Publication)
+			events = events[1:]
+		}
+	}
+	m.events[e.Topic] = events
+
+	return nil
+}
+
+func (m *memoryEventStore) Events(q *EventQuery) ([]*StoredEvent, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	lo, hi, err := q.bounds(func(id ID) (uint64, bool) {
+		seq, ok := m.publications[id]
+		return seq, ok
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []*StoredEvent{}
+	for t, events := range m.events {
+		if !q.matches(t) {
+			continue
+		}
+		for _, e := range events {
+			if e.Sequence >= lo && e.Sequence <= hi && q.inTime(e.Timestamp) {
+				matched = append(matched, e)
+			}
+		}
+	}
+
+	sort.Sort(bySequence(matched))
+	if q.Limit > 0 && len(matched) > q.Limit {
+		if q.ascending() {
+			matched = matched[:q.Limit]
+		} else {
+			matched = matched[len(matched)-q.Limit:]
+		}
+	}
+	reverse(matched)
+
+	return matched, nil
+}
+
+func (m *memoryEventStore) Close() error {
+	return nil
+}
+
+type bySequence []*StoredEvent
+
+func (e bySequence) Len() int           { return len(e) }
+func (e bySequence) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e bySequence) Less(i, j int) bool { return e[i].Sequence < e[j].Sequence }
+
+func reverse(events []*StoredEvent) {
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventHistoryRetentionPolicies(t *testing.T) {
+	policies := []RetentionPolicy{
+		{URI: URI("com.myapp.state"), Limit: 2},
+		{URI: URI("com.myapp.metrics."), Match: PREFIX, MaxAge: 60},
+	}
+	h := newEventHistory(policies, NewMemoryEventStore(policies))
+
+	now := time.Now()
+	h.store(&StoredEvent{Publication: ID(10), Timestamp: now.Add(-time.Hour), Topic: Topic("com.myapp.metrics.cpu")})
+	h.store(&StoredEvent{Publication: ID(11), Timestamp: now, Topic: Topic("com.myapp.metrics.mem")})
+	h.store(&StoredEvent{Publication: ID(20), Timestamp: now, Topic: Topic("com.myapp.chat")})
+	for i := 1; i <= 3; i++ {
+		h.store(&StoredEvent{Publication: ID(i), Timestamp: now.Add(time.Duration(i) * time.Millisecond), Topic: Topic("com.myapp.state")})
+	}
+
+	events, _ := h.history(&EventQuery{Topic: Topic("com.myapp.state")})
+	if len(events) != 2 || events[0].Publication != ID(3) || events[1].Publication != ID(2) {
+		t.Error("Unexpected limited history ", events)
+	}
+
+	events, _ = h.history(&EventQuery{Match: PREFIX, Topic: Topic("com.myapp.")})
+	if len(events) != 3 {
+		t.Error("Unexpected prefix history size ", len(events))
+	}
+	for _, e := range events {
+		if e.Publication == ID(10) || e.Publication == ID(20) {
+			t.Error("Unexpected expired or not retained event ", e.Publication)
+		}
+	}
+
+	events, _ = h.history(&EventQuery{Match: PREFIX, Topic: Topic("com.myapp."), Limit: 1})
+	if len(events) != 1 || events[0].Publication != ID(3) {
+		t.Error("Unexpected limited prefix history ", events)
+	}
+
+	retained := h.retained(EXACT, Topic("com.myapp.state"))
+	if len(retained) != 1 || retained[0].Publication != ID(3) {
+		t.Error("Unexpected retained event ", retained)
+	}
+	if len(h.retained(EXACT, Topic("com.myapp.chat"))) != 0 {
+		t.Error("Unexpected retained event without retention policy")
+	}
+}
+
+func TestMemoryEventStorePaging(t *testing.T) {
+	testEventStorePaging(t, NewMemoryEventStore(nil))
+}
+
+// testEventStorePaging stores publications 1 to 10 alternating a and b topics
+func testEventStorePaging(t *testing.T, store EventStore) {
+	now := time.Now()
+	for i := 1; i <= 10; i++ {
+		topic := Topic("com.myapp.a")
+		if i%2 == 0 {
+			topic = Topic("com.myapp.b")
+		}
+		err := store.Store(&StoredEvent{
+			Publication: ID(i * 100),
+			Timestamp:   now.Add(time.Duration(i) * time.Second),
+			Topic:       topic,
+			Arguments:   []interface{}{"event"},
+		})
+		if err != nil {
+			t.Fatal("Unexpected error storing event ", err)
+		}
+	}
+
+	publications := func(q *EventQuery) []ID {
+		events, err := store.Events(q)
+		if err != nil {
+			t.Fatal("Unexpected error querying events ", err)
+		}
+		ids := []ID{}
+		for _, e := range events {
+			ids = append(ids, e.Publication)
+		}
+		return ids
+	}
+	expect := func(name string, ids []ID, expected ...ID) {
+		if len(ids) != len(expected) {
+			t.Error("Unexpected ", name, " events ", ids)
+			return
+		}
+		for i := range ids {
+			if ids[i] != expected[i] {
+				t.Error("Unexpected ", name, " events ", ids)
+				return
+			}
+		}
+	}
+
+	expect("latest", publications(&EventQuery{Match: PREFIX, Topic: Topic("com.myapp."), Limit: 3}), 1000, 900, 800)
+	expect("topic", publications(&EventQuery{Topic: Topic("com.myapp.a"), Limit: 2}), 900, 700)
+	expect("before", publications(&EventQuery{Topic: Topic("com.myapp.a"), Before: ID(700), Limit: 2}), 500, 300)
+	expect("after", publications(&EventQuery{Topic: Topic("com.myapp.b"), After: ID(300), Limit: 2}), 600, 400)
+	expect("range", publications(&EventQuery{Match: PREFIX, Topic: Topic("com.myapp."), Since: now.Add(4 * time.Second), Until: now.Add(6 * time.Second)}), 600, 500, 400)
+
+	for _, q := range []*EventQuery{{Before: ID(12345)}, {After: ID(12345)}} {
+		_, err := store.Events(q)
+		if h, ok := err.(*handlerError); !ok || h.uri != URI("wamp.error.invalid_argument") {
+			t.Error("Expected invalid argument error on unknown publication ", err)
+		}
+	}
+}
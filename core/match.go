@@ -0,0 +1,42 @@
+package core
+
+import (
+	"strings"
+)
+
+// URI match policies on subscriptions, registrations and authorization rules
+const (
+	EXACT    = "exact"
+	PREFIX   = "prefix"
+	WILDCARD = "wildcard"
+)
+
+func validMatchPolicy(policy string) bool {
+	return policy == EXACT || policy == PREFIX || policy == WILDCARD
+}
+
+// matchURI checks uri against pattern, on wildcard policy void
+// pattern components match any uri component
+func matchURI(policy string, pattern, uri string) bool {
+	switch policy {
+	case "", EXACT:
+		return pattern == uri
+	case PREFIX:
+		return strings.HasPrefix(uri, pattern)
+	case WILDCARD:
+		p := strings.Split(pattern, ".")
+		u := strings.Split(uri, ".")
+		if len(p) != len(u) {
+			return false
+		}
+		for i := range p {
+			if p[i] != "" && p[i] != u[i] {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return false
+	}
+}
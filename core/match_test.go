@@ -0,0 +1,28 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestMatchURI(t *testing.T) {
+	cases := []struct {
+		policy  string
+		pattern string
+		uri     string
+		match   bool
+	}{
+		{EXACT, "com.myapp.foo", "com.myapp.foo", true},
+		{EXACT, "com.myapp.foo", "com.myapp.foo.bar", false},
+		{PREFIX, "com.myapp", "com.myapp.foo", true},
+		{PREFIX, "com.myapp", "com.other.foo", false},
+		{WILDCARD, "com..foo", "com.myapp.foo", true},
+		{WILDCARD, "com..foo", "com.myapp.bar", false},
+		{WILDCARD, "com..foo", "com.myapp.foo.bar", false},
+	}
+
+	for _, c := range cases {
+		if matchURI(c.policy, c.pattern, c.uri) != c.match {
+			t.Error("Unexpected match result ", c)
+		}
+	}
+}
@@ -5,16 +5,17 @@ import (
 	"sync"
 )
 
+// MetaEvent is published on its own meta event topic
 type MetaEvent struct {
-	topic   Topic
-	peerID  PeerID
-	msg     URI
-	details map[string]interface{}
+	topic       Topic
+	peerID      PeerID
+	arguments   []interface{}
+	argumentsKw map[string]interface{}
 }
 
 type SessionMetaEventHandler interface {
-	Fire(PeerID, URI, map[string]interface{})
-	Consume(r *DefaultRouter)
+	Fire(PeerID, Topic, []interface{}, map[string]interface{})
+	Consume(r *Realm)
 	Terminate()
 }
 
@@ -32,23 +33,23 @@ func NewSessionMetaEventsHandler() *defaultSessionMetaEventHandler {
 	}
 }
 
-func (s *defaultSessionMetaEventHandler) Fire(id PeerID, message URI, details map[string]interface{}) {
-	//Fire on_join Session Meta Event only if is not the internal peer
+func (s *defaultSessionMetaEventHandler) Fire(id PeerID, topic Topic, arguments []interface{}, argumentsKw map[string]interface{}) {
+	//Fire Session Meta Event only if is not the internal peer
 	if id == PeerID("internal") {
 		return
 	}
 	// fired in a non blocking way
 	go func() {
 		s.metaEvents <- &MetaEvent{
-			topic:   Topic("wampire.session.meta.events"),
-			peerID:  id,
-			msg:     message,
-			details: details,
+			topic:       topic,
+			peerID:      id,
+			arguments:   arguments,
+			argumentsKw: argumentsKw,
 		}
 	}()
 }
 
-func (s *defaultSessionMetaEventHandler) Consume(r *DefaultRouter) {
+func (s *defaultSessionMetaEventHandler) Consume(r *Realm) {
 	defer log.Println("Closed fireMetaEvents Loop")
 	for {
 		select {
@@ -59,16 +60,11 @@ func (s *defaultSessionMetaEventHandler) Consume(r *DefaultRouter) {
 
 			r.Broker.Publish(
 				&Publish{
-					Request: NewId(),
-					Topic:   mec.topic,
-					Options: map[string]interface{}{
-						"session_id":  mec.peerID,
-						"acknowledge": true,
-						"details":     mec.details,
-					},
-					Arguments: []interface{}{
-						map[string]interface{}{"message": mec.msg},
-					},
+					Request:     r.internalSession.session.nextRequestId(),
+					Topic:       mec.topic,
+					Options:     map[string]interface{}{},
+					Arguments:   mec.arguments,
+					ArgumentsKw: mec.argumentsKw,
 				}, r.internalSession.session)
 		case <-s.done:
 			return
@@ -84,6 +80,6 @@ func (s *defaultSessionMetaEventHandler) Terminate() {
 /** Fake Session Meta Events Handler to be used as Stub **/
 type fakeSessionMetaEventsHandler struct{}
 
-func (*fakeSessionMetaEventsHandler) Fire(PeerID, URI, map[string]interface{}) {}
-func (*fakeSessionMetaEventsHandler) Consume(r *DefaultRouter)                 {}
-func (*fakeSessionMetaEventsHandler) Terminate()                               {}
+func (*fakeSessionMetaEventsHandler) Fire(PeerID, Topic, []interface{}, map[string]interface{}) {}
+func (*fakeSessionMetaEventsHandler) Consume(r *Realm)                                          {}
+func (*fakeSessionMetaEventsHandler) Terminate()                                                {}
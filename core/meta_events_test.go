@@ -3,44 +3,57 @@ package core
 import (
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestMetaEventsFireAndConsume(t *testing.T) {
 	m := NewSessionMetaEventsHandler()
-	m.Fire(PeerID("fake"), URI("fake_test"), map[string]interface{}{"foo": "bar"})
+	m.Fire(PeerID("fake"), Topic("wamp.session.on_leave"), []interface{}{ID(1), "alice", "user"}, map[string]interface{}{"reason": URI("wamp.close.normal")})
 
-	router := &DefaultRouter{
+	realm := &Realm{
 		sessions:        make(map[PeerID]*Session),
 		mutex:           &sync.RWMutex{},
-		Broker:          NewBroker(m),
+		Broker:          NewBroker(m, NewRuleAuthorizer(DefaultRules())),
 		exit:            make(chan struct{}),
 		metaEvents:      m,
 		internalSession: newInSession(),
 	}
 
 	s := NewSession(NewFakePeer(PeerID("123")))
-	subs := &Subscribe{Request: ID(123), Topic: Topic("wampire.session.meta.events")}
-	router.Subscribe(subs, s)
+	subs := &Subscribe{Request: ID(123), Topic: Topic("wamp.session.on_leave")}
+	realm.Subscribe(subs, s)
 	r := <-s.Receive()
 
 	if r.MsgType() != SUBSCRIBED {
 		t.Error("Error subscribing")
 	}
 
-	go m.Consume(router)
+	go m.Consume(realm)
 
 	r = <-s.Receive()
 	if r.MsgType() != EVENT {
-		t.Error("Error subscribing")
+		t.Fatal("Unexpected message type ", r.MsgType())
 	}
 
-	if r.(*Event).Arguments[0].(map[string]interface{})["message"].(URI) != URI("fake_test") {
-		t.Error("Unexpected details")
+	e := r.(*Event)
+	if len(e.Arguments) != 3 || e.Arguments[0] != ID(1) || e.Arguments[1] != "alice" {
+		t.Error("Unexpected arguments ", e.Arguments)
+	}
+	if e.ArgumentsKw["reason"] != URI("wamp.close.normal") {
+		t.Error("Unexpected keyword arguments ", e.ArgumentsKw)
 	}
-	if r.(*Event).Details["details"].(map[string]interface{})["foo"] != "bar" {
-		t.Error("Unexpected details")
+	if e.Details["topic"] != Topic("wamp.session.on_leave") {
+		t.Error("Unexpected details ", e.Details)
 	}
-	if r.(*Event).Details["session_id"].(PeerID) != PeerID("fake") {
-		t.Error("Unexpected details")
+}
+
+func TestMetaEventsSkipInternalSession(t *testing.T) {
+	m := NewSessionMetaEventsHandler()
+	m.Fire(PeerID("internal"), Topic("wamp.session.on_join"), nil, nil)
+
+	select {
+	case <-m.metaEvents:
+		t.Error("Unexpected internal session meta event")
+	case <-time.After(time.Millisecond * 50):
 	}
 }
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"crypto/x509"
 	"github.com/gorilla/websocket"
 	"log"
+	"net/http"
 	"sync"
 	"time"
 )
@@ -23,28 +25,43 @@ type Peer interface {
 	Terminate()
 }
 
+// HTTPPeer exposes upgrade request from websocket server peers
+type HTTPPeer interface {
+	Request() *http.Request
+}
+
+// TransportPeer describes peer transport on session meta events
+type TransportPeer interface {
+	Transport() map[string]interface{}
+}
+
 type webSocketPeer struct {
-	id         PeerID
-	conn       *websocket.Conn
-	receive    chan Message
-	send       chan Message
-	closedConn chan struct{}
-	exit       chan struct{}
-	serializer Serializer
-	wg         *sync.WaitGroup
-	mutex      sync.Mutex
+	id          PeerID
+	conn        *websocket.Conn
+	request     *http.Request
+	receive     chan Message
+	send        chan Message
+	closedConn  chan struct{}
+	exit        chan struct{}
+	serializer  Serializer
+	payloadType int
+	wg          *sync.WaitGroup
+	mutex       sync.Mutex
 }
 
+// NewWebsockerPeer selects serializer from negotiated subprotocol
 func NewWebsockerPeer(conn *websocket.Conn, mode string) *webSocketPeer {
+	serializer, payloadType := newSerializer(conn.Subprotocol())
 	p := &webSocketPeer{
-		serializer: NewJSONSerializer(),
-		receive:    make(chan Message),
-		send:       make(chan Message),
-		exit:       make(chan struct{}),
-		closedConn: make(chan struct{}),
-		conn:       conn,
-		id:         NewStringId(),
-		wg:         &sync.WaitGroup{},
+		serializer:  serializer,
+		payloadType: payloadType,
+		receive:     make(chan Message),
+		send:        make(chan Message),
+		exit:        make(chan struct{}),
+		closedConn:  make(chan struct{}),
+		conn:        conn,
+		id:          NewStringId(),
+		wg:          &sync.WaitGroup{},
 	}
 	p.conn.SetReadLimit(maxMessageSize)
 
@@ -86,6 +103,26 @@ func (p *webSocketPeer) ID() PeerID {
 	return p.id
 }
 
+func (p *webSocketPeer) Request() *http.Request {
+	return p.request
+}
+
+func (p *webSocketPeer) Transport() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "websocket",
+		"protocol": p.conn.Subprotocol(),
+		"peer":     p.conn.RemoteAddr().String(),
+	}
+}
+
+func (p *webSocketPeer) ClientCertificate() *x509.Certificate {
+	if p.request == nil {
+		return nil
+	}
+
+	return verifiedCertificate(p.request.TLS)
+}
+
 func (p *webSocketPeer) Terminate() {
 	close(p.send)
 	time.Sleep(time.Millisecond * 100) // give enough time to send close frame
@@ -112,7 +149,7 @@ func (p *webSocketPeer) writeLoop(mode string) {
 			if err != nil {
 				log.Fatal(err)
 			}
-			if err := p.write(websocket.TextMessage, data); err != nil {
+			if err := p.write(p.payloadType, data); err != nil {
 				return
 			}
 		case <-ticker.C:
@@ -142,21 +179,38 @@ func (p *webSocketPeer) readLoop() {
 	}()
 
 	for {
-		_, data, err := p.conn.ReadMessage()
+		mt, data, err := p.conn.ReadMessage()
 		if err != nil {
 			log.Println("Error reading Message on websocket Client", err)
 			return
 		}
+		// binary serializers require binary frames, text ones text frames
+		if mt != p.payloadType {
+			log.Println("Unexpected websocket frame type ", mt, " on peer ", p.id)
+			continue
+		}
 		message, err := p.serializer.Deserialize(data)
 		if err != nil {
-			log.Println(string(data))
-			log.Fatal("Fatal on deserialize ", err)
-
+			log.Println("Error on deserialize ", err, " on peer ", p.id)
+			continue
 		}
 		p.receive <- message
 	}
 }
 
+// newSerializer returns subprotocol serializer and websocket frame type,
+// JSON is used when no subprotocol was negotiated
+func newSerializer(subprotocol string) (Serializer, int) {
+	switch subprotocol {
+	case MSGPACK_SUBPROTOCOL:
+		return NewMsgPackSerializer(), websocket.BinaryMessage
+	case CBOR_SUBPROTOCOL:
+		return NewCBORSerializer(), websocket.BinaryMessage
+	default:
+		return NewJSONSerializer(), websocket.TextMessage
+	}
+}
+
 func (p *webSocketPeer) write(mt int, message []byte) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
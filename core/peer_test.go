@@ -31,3 +31,34 @@ func (p *fakePeer) ID() PeerID {
 func (p *fakePeer) Terminate() {
 
 }
+
+// pipePeer splits peer directions, test writes on in and reads from out
+type pipePeer struct {
+	in  chan Message
+	out chan Message
+	id  PeerID
+}
+
+func NewPipePeer(id PeerID) *pipePeer {
+	return &pipePeer{
+		in:  make(chan Message, 10),
+		out: make(chan Message, 10),
+		id:  id,
+	}
+}
+
+func (p *pipePeer) Send(m Message) {
+	p.out <- m
+}
+
+func (p *pipePeer) Receive() chan Message {
+	return p.in
+}
+
+func (p *pipePeer) ID() PeerID {
+	return p.id
+}
+
+func (p *pipePeer) Terminate() {
+
+}
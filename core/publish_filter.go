@@ -0,0 +1,89 @@
+package core
+
+// publishFilter applies subscriber black and white listing and publisher
+// exclusion, nil eligible sets allow any session
+type publishFilter struct {
+	exclude          map[ID]bool
+	excludeAuthID    map[string]bool
+	excludeAuthRole  map[string]bool
+	eligible         map[ID]bool
+	eligibleAuthID   map[string]bool
+	eligibleAuthRole map[string]bool
+	excludeMe        bool
+}
+
+func newPublishFilter(options map[string]interface{}) *publishFilter {
+	f := &publishFilter{
+		exclude:          idSet(options["exclude"]),
+		excludeAuthID:    stringSet(options["exclude_authid"]),
+		excludeAuthRole:  stringSet(options["exclude_authrole"]),
+		eligible:         idSet(options["eligible"]),
+		eligibleAuthID:   stringSet(options["eligible_authid"]),
+		eligibleAuthRole: stringSet(options["eligible_authrole"]),
+		excludeMe:        true,
+	}
+	if v, ok := options["exclude_me"].(bool); ok {
+		f.excludeMe = v
+	}
+
+	return f
+}
+
+// allows checks if subscriber session receives publisher event,
+// publisher not excluded by exclude_me applies the same lists
+func (f *publishFilter) allows(s *Session, publisher *Session) bool {
+	if s.ID() == publisher.ID() && f.excludeMe {
+		return false
+	}
+
+	var authID, authRole string
+	if s.identity != nil {
+		authID, authRole = s.identity.AuthID, s.identity.AuthRole
+	}
+	if f.exclude[s.sessionID] || f.excludeAuthID[authID] || f.excludeAuthRole[authRole] {
+		return false
+	}
+	if f.eligible != nil && !f.eligible[s.sessionID] {
+		return false
+	}
+	if f.eligibleAuthID != nil && !f.eligibleAuthID[authID] {
+		return false
+	}
+	if f.eligibleAuthRole != nil && !f.eligibleAuthRole[authRole] {
+		return false
+	}
+
+	return true
+}
+
+func idSet(v interface{}) map[ID]bool {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	set := map[ID]bool{}
+	for _, item := range list {
+		if id, ok := toInt(item); ok {
+			set[ID(id)] = true
+		}
+	}
+
+	return set
+}
+
+func stringSet(v interface{}) map[string]bool {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	set := map[string]bool{}
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+
+	return set
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPublishFilter(t *testing.T) {
+	publisher := newFilterSession("publisher", 1, "joe", "frontend")
+	sessions := []*Session{
+		newFilterSession("a", 2, "ann", "frontend"),
+		newFilterSession("b", 3, "bob", "backend"),
+		newFilterSession("c", 4, "carl", "frontend"),
+	}
+
+	cases := []struct {
+		options  map[string]interface{}
+		expected []bool // publisher, a, b, c
+	}{
+		{map[string]interface{}{}, []bool{false, true, true, true}},
+		{map[string]interface{}{"exclude_me": false}, []bool{true, true, true, true}},
+		{map[string]interface{}{"exclude": []interface{}{float64(2)}}, []bool{false, false, true, true}},
+		{map[string]interface{}{"eligible": []interface{}{uint64(3), uint64(4)}}, []bool{false, false, true, true}},
+		{map[string]interface{}{"exclude_authid": []interface{}{"carl"}}, []bool{false, true, true, false}},
+		{map[string]interface{}{"eligible_authrole": []interface{}{"frontend"}}, []bool{false, true, false, true}},
+		{map[string]interface{}{"eligible_authrole": []interface{}{"frontend"}, "exclude_authrole": []interface{}{}, "exclude": []interface{}{4}}, []bool{false, true, false, false}},
+		{map[string]interface{}{"eligible_authid": []interface{}{}}, []bool{false, false, false, false}},
+		{map[string]interface{}{"exclude_me": false, "exclude": []interface{}{1}}, []bool{false, true, true, true}},
+		{map[string]interface{}{"exclude_me": false, "eligible_authrole": []interface{}{"backend"}}, []bool{false, false, true, false}},
+		{map[string]interface{}{"exclude_me": false, "exclude_authid": []interface{}{"ann"}}, []bool{true, false, true, true}},
+	}
+
+	for i, c := range cases {
+		f := newPublishFilter(c.options)
+		for j, s := range append([]*Session{publisher}, sessions...) {
+			if f.allows(s, publisher) != c.expected[j] {
+				t.Error(fmt.Sprintf("Case %d unexpected filter result on session %s", i, s.ID()))
+			}
+		}
+	}
+}
+
+func newFilterSession(id string, sessionID ID, authID, authRole string) *Session {
+	s := NewSession(NewFakePeer(PeerID(id)))
+	s.sessionID = sessionID
+	s.identity = &Identity{AuthID: authID, AuthRole: authRole}
+
+	return s
+}
@@ -0,0 +1,332 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// WAMP RawSocket transport, 4 octets handshake and length prefixed frames
+const (
+	rawSocketMagic            = 0x7F
+	rawSocketJSON             = 1
+	rawSocketMsgPack          = 2
+	rawSocketCBOR             = 3
+	rawSocketRegular          = 0
+	rawSocketPing             = 1
+	rawSocketPong             = 2
+	rawSocketErrSerializer    = 1
+	rawSocketErrReserved      = 3
+	rawSocketHandshakeTimeout = time.Second * 1
+)
+
+type rawSocketPeer struct {
+	id         PeerID
+	conn       net.Conn
+	serializer Serializer
+	maxLength  int // max message length accepted by remote peer
+	receive    chan Message
+	send       chan Message
+	closedConn chan struct{}
+	exit       chan struct{}
+	wg         *sync.WaitGroup
+	mutex      sync.Mutex
+}
+
+func newRawSocketPeer(conn net.Conn, s Serializer, maxLength int, mode string) *rawSocketPeer {
+	p := &rawSocketPeer{
+		id:         NewStringId(),
+		conn:       conn,
+		serializer: s,
+		maxLength:  maxLength,
+		receive:    make(chan Message),
+		send:       make(chan Message),
+		closedConn: make(chan struct{}),
+		exit:       make(chan struct{}),
+		wg:         &sync.WaitGroup{},
+	}
+
+	p.wg.Add(2)
+	go p.writeLoop(mode)
+	go p.readLoop(mode)
+
+	return p
+}
+
+// AcceptRawSocket runs server side handshake on an accepted connection
+func AcceptRawSocket(conn net.Conn) (*rawSocketPeer, error) {
+	conn.SetDeadline(time.Now().Add(rawSocketHandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	hs := make([]byte, 4)
+	if _, err := io.ReadFull(conn, hs); err != nil {
+		return nil, err
+	}
+	if hs[0] != rawSocketMagic {
+		return nil, fmt.Errorf("Invalid rawsocket magic octet %x", hs[0])
+	}
+	if hs[2] != 0 || hs[3] != 0 {
+		conn.Write(rawSocketHandshakeError(rawSocketErrReserved))
+		return nil, fmt.Errorf("Use of rawsocket reserved octets")
+	}
+
+	serializer, ok := newRawSocketSerializer(hs[1] & 0x0F)
+	if !ok {
+		conn.Write(rawSocketHandshakeError(rawSocketErrSerializer))
+		return nil, fmt.Errorf("Unsupported rawsocket serializer %d", hs[1]&0x0F)
+	}
+
+	reply := []byte{rawSocketMagic, rawSocketLengthExponent(maxMessageSize)<<4 | hs[1]&0x0F, 0, 0}
+	if _, err := conn.Write(reply); err != nil {
+		return nil, err
+	}
+
+	return newRawSocketPeer(conn, serializer, rawSocketMaxLength(hs[1]>>4), SERVER), nil
+}
+
+// DialRawSocket connects to a rawsocket router on tcp or unix network
+func DialRawSocket(network, address string, serializer byte) (*rawSocketPeer, error) {
+	s, ok := newRawSocketSerializer(serializer)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported rawsocket serializer %d", serializer)
+	}
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return dialRawSocketHandshake(conn, s, serializer)
+}
+
+// DialRawSocketTLS connects to a TLS rawsocket router on tcp network
+func DialRawSocketTLS(address string, serializer byte, config *tls.Config) (*rawSocketPeer, error) {
+	s, ok := newRawSocketSerializer(serializer)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported rawsocket serializer %d", serializer)
+	}
+
+	conn, err := tls.Dial("tcp", address, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return dialRawSocketHandshake(conn, s, serializer)
+}
+
+// dialRawSocketHandshake runs client side handshake on a dialed connection
+func dialRawSocketHandshake(conn net.Conn, s Serializer, serializer byte) (*rawSocketPeer, error) {
+	conn.SetDeadline(time.Now().Add(rawSocketHandshakeTimeout))
+	defer conn.SetDeadline(time.Time{})
+
+	hs := []byte{rawSocketMagic, rawSocketLengthExponent(maxMessageSize)<<4 | serializer, 0, 0}
+	if _, err := conn.Write(hs); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reply := make([]byte, 4)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply[0] != rawSocketMagic {
+		conn.Close()
+		return nil, fmt.Errorf("Invalid rawsocket magic octet %x", reply[0])
+	}
+	if reply[1]&0x0F == 0 {
+		conn.Close()
+		return nil, fmt.Errorf("Rawsocket handshake error %d", reply[1]>>4)
+	}
+
+	return newRawSocketPeer(conn, s, rawSocketMaxLength(reply[1]>>4), CLIENT), nil
+}
+
+func (p *rawSocketPeer) Send(msg Message) {
+	defer func() {
+		//hacky way to solve close of a closed channel
+		if r := recover(); r != nil {
+			log.Println("Recovered in close defer!!! ", r)
+		}
+	}()
+	p.send <- msg
+}
+
+func (p *rawSocketPeer) Receive() chan Message {
+	return p.receive
+}
+
+func (p *rawSocketPeer) ID() PeerID {
+	return p.id
+}
+
+func (p *rawSocketPeer) Transport() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "rawsocket",
+		"peer": p.conn.RemoteAddr().String(),
+	}
+}
+
+func (p *rawSocketPeer) ClientCertificate() *x509.Certificate {
+	conn, ok := p.conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := conn.ConnectionState()
+
+	return verifiedCertificate(&state)
+}
+
+func (p *rawSocketPeer) Terminate() {
+	close(p.send)
+	time.Sleep(time.Millisecond * 100) // give enough time to flush pending writes
+	close(p.exit)
+
+	p.conn.Close()
+	p.wg.Wait()
+	log.Println("rawSocketPeer EXITED", string(p.id))
+}
+
+func (p *rawSocketPeer) writeLoop(mode string) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	defer p.wg.Done()
+
+	for {
+		select {
+		case message, ok := <-p.send:
+			if !ok {
+				return
+			}
+			data, err := p.serializer.Serialize(message)
+			if err != nil {
+				log.Println("Error serializing message ", err)
+				continue
+			}
+			if err := p.write(rawSocketRegular, data); err != nil {
+				log.Println("Error writting rawsocket message ", err)
+				return
+			}
+		case <-ticker.C:
+			if mode == SERVER {
+				if err := p.write(rawSocketPing, []byte{}); err != nil {
+					log.Println("Error writting Ping message", err)
+					return
+				}
+			}
+		//exit from readLoop Down
+		case <-p.closedConn:
+			log.Println("writeLoop closedConn chan close")
+			return
+		// exit from terminate
+		case <-p.exit:
+			log.Println("writeLoop exit chan close")
+			return
+		}
+	}
+}
+
+func (p *rawSocketPeer) readLoop(mode string) {
+	defer func() {
+		p.wg.Done()
+		close(p.closedConn)
+		close(p.receive)
+	}()
+
+	header := make([]byte, 4)
+	for {
+		if mode == SERVER {
+			p.conn.SetReadDeadline(time.Now().Add(pongWait))
+		}
+		if _, err := io.ReadFull(p.conn, header); err != nil {
+			log.Println("Error reading rawsocket frame header ", err)
+			return
+		}
+
+		length := int(binary.BigEndian.Uint32(header) & 0x00FFFFFF)
+		if length > maxMessageSize {
+			log.Println("Rawsocket frame length exceeded ", length, " on peer ", p.id)
+			return
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(p.conn, data); err != nil {
+			log.Println("Error reading rawsocket frame ", err)
+			return
+		}
+
+		switch header[0] & 0x07 {
+		case rawSocketRegular:
+			message, err := p.serializer.Deserialize(data)
+			if err != nil {
+				log.Println("Error on deserialize ", err, " on peer ", p.id)
+				continue
+			}
+			p.receive <- message
+		case rawSocketPing:
+			if err := p.write(rawSocketPong, data); err != nil {
+				log.Println("Error writting Pong message", err)
+				return
+			}
+		case rawSocketPong:
+		default:
+			log.Println("Unexpected rawsocket frame type ", header[0], " on peer ", p.id)
+			return
+		}
+	}
+}
+
+func (p *rawSocketPeer) write(frameType byte, payload []byte) error {
+	if len(payload) > p.maxLength {
+		return fmt.Errorf("Message length %d exceeds peer max length %d", len(payload), p.maxLength)
+	}
+
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	frame[0] = frameType
+	copy(frame[4:], payload)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	_, err := p.conn.Write(frame)
+
+	return err
+}
+
+func newRawSocketSerializer(serializer byte) (Serializer, bool) {
+	switch serializer {
+	case rawSocketJSON:
+		return NewJSONSerializer(), true
+	case rawSocketMsgPack:
+		return NewMsgPackSerializer(), true
+	case rawSocketCBOR:
+		return NewCBORSerializer(), true
+	default:
+		return nil, false
+	}
+}
+
+func rawSocketHandshakeError(code byte) []byte {
+	return []byte{rawSocketMagic, code << 4, 0, 0}
+}
+
+// rawSocketMaxLength decodes handshake length exponent, 2^(9+exp) octets
+func rawSocketMaxLength(exp byte) int {
+	return 1 << (9 + uint(exp))
+}
+
+func rawSocketLengthExponent(length int) byte {
+	var exp byte
+	for exp < 15 && rawSocketMaxLength(exp) < length {
+		exp++
+	}
+
+	return exp
+}
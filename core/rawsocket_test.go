@@ -0,0 +1,117 @@
+package core
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRawSocketSessionOverTCP(t *testing.T) {
+	s := NewServer(DefaultConfig())
+	defer s.router.Terminate()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ServeRawSocket(ln)
+	defer ln.Close()
+
+	p, err := DialRawSocket("tcp", ln.Addr().String(), rawSocketMsgPack)
+	if err != nil {
+		t.Fatal("Unexpected error dialing rawsocket ", err)
+	}
+	assertRawSocketWelcome(t, p)
+}
+
+func TestRawSocketSessionOverUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wampire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewServer(DefaultConfig())
+	defer s.router.Terminate()
+	path := filepath.Join(dir, "wampire.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ServeRawSocket(ln)
+	defer ln.Close()
+
+	p, err := DialRawSocket("unix", path, rawSocketJSON)
+	if err != nil {
+		t.Fatal("Unexpected error dialing rawsocket ", err)
+	}
+	assertRawSocketWelcome(t, p)
+}
+
+func TestRawSocketIgnoresShortFrames(t *testing.T) {
+	s := NewServer(DefaultConfig())
+	defer s.router.Terminate()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ServeRawSocket(ln)
+	defer ln.Close()
+
+	p, err := DialRawSocket("tcp", ln.Addr().String(), rawSocketJSON)
+	if err != nil {
+		t.Fatal("Unexpected error dialing rawsocket ", err)
+	}
+	for _, frame := range []string{"[]", "[1]"} {
+		if err := p.write(rawSocketRegular, []byte(frame)); err != nil {
+			t.Fatal("Unexpected error writing frame ", err)
+		}
+	}
+
+	// connection keeps serving after invalid frames
+	assertRawSocketWelcome(t, p)
+}
+
+func TestRawSocketUnsupportedSerializer(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go AcceptRawSocket(server)
+
+	client.Write([]byte{rawSocketMagic, 0xF9, 0, 0})
+	reply := make([]byte, 4)
+	if _, err := client.Read(reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply[0] != rawSocketMagic || reply[1] != rawSocketErrSerializer<<4 {
+		t.Error("Unexpected handshake error reply ", reply)
+	}
+}
+
+func TestRawSocketLengthExponent(t *testing.T) {
+	if rawSocketLengthExponent(512) != 0 || rawSocketLengthExponent(maxMessageSize) != 11 {
+		t.Error("Unexpected length exponent")
+	}
+	if rawSocketLengthExponent(1<<30) != 15 {
+		t.Error("Unexpected max length exponent")
+	}
+}
+
+func assertRawSocketWelcome(t *testing.T, p *rawSocketPeer) {
+	session := NewSession(p)
+	defer session.Terminate()
+
+	session.Send(&Hello{Realm: defaultRealm, Details: map[string]interface{}{}})
+	r := <-session.Receive()
+	if r.MsgType() != WELCOME {
+		t.Fatal("Unexpected hello response ", r.MsgType())
+	}
+
+	// call unregistered procedure through rawsocket, expecting error
+	session.Send(&Call{Request: ID(1), Options: map[string]interface{}{}, Procedure: URI("com.myapp.none")})
+	r = <-session.Receive()
+	if r.MsgType() != ERROR {
+		t.Error("Unexpected call response ", r.MsgType())
+	}
+	session.Send(&Goodbye{Details: map[string]interface{}{}, Reason: URI("wamp.close.normal")})
+}
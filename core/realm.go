@@ -0,0 +1,153 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Realm isolates sessions, each realm owns its broker, dealer
+// and session meta events
+type Realm struct {
+	name        URI
+	authMethods []string
+	sessions    map[PeerID]*Session
+	Broker
+	Dealer
+	exit            chan struct{}
+	mutex           *sync.RWMutex
+	internalSession *inSession
+	metaEvents      SessionMetaEventHandler
+	authorizer      Authorizer
+	eventStore      EventStore
+}
+
+const authorizationReloadPeriod = time.Second * 5
+
+func NewRealm(c RealmConfig) (*Realm, error) {
+	if !validDisclosurePolicy(c.Disclosure) {
+		return nil, fmt.Errorf("Invalid disclosure policy %s on realm %s", c.Disclosure, c.Name)
+	}
+	for _, p := range c.EventHistory {
+		if p.Match != "" && !validMatchPolicy(p.Match) {
+			return nil, fmt.Errorf("Invalid match policy %s on event history %s", p.Match, p.URI)
+		}
+	}
+
+	a := NewRuleAuthorizer(DefaultRules())
+	if c.Authorization != "" {
+		var err error
+		a, err = LoadRuleAuthorizer(c.Authorization)
+		if err != nil {
+			return nil, err
+		}
+		go a.Watch(authorizationReloadPeriod)
+	}
+
+	m := NewSessionMetaEventsHandler()
+	b := NewBroker(m, a)
+	b.disclosure = c.Disclosure
+	events := c.EventStore
+	if len(c.EventHistory) > 0 {
+		if events == nil && c.EventStoreDir != "" {
+			var err error
+			events, err = NewFileEventStore(c.EventStoreDir, c.EventStoreSegmentSize, c.EventStoreSegments, c.EventHistory)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if events == nil {
+			events = NewMemoryEventStore(c.EventHistory)
+		}
+		b.history = newEventHistory(c.EventHistory, events)
+	}
+	d := NewDealer(m, a)
+	d.callTimeout = time.Duration(c.CallTimeout) * time.Millisecond
+	d.disclosure = c.Disclosure
+	r := &Realm{
+		name:            c.Name,
+		authMethods:     c.AuthMethods,
+		sessions:        make(map[PeerID]*Session),
+		Broker:          b,
+		Dealer:          d,
+		exit:            make(chan struct{}),
+		mutex:           &sync.RWMutex{},
+		internalSession: newInSession(),
+		metaEvents:      m,
+		authorizer:      a,
+		eventStore:      events,
+	}
+
+	// Handle Session Meta Events
+	go m.Consume(r)
+
+	// Register in session procedures
+	r.Dealer.RegisterSessionHandlers(r.internalSession.Handlers(), r.internalSession)
+	r.Dealer.RegisterSessionHandlers(r.Handlers(), r.internalSession)
+	r.Dealer.RegisterSessionHandlers(r.Broker.Handlers(), r.internalSession)
+	r.Dealer.RegisterSessionHandlers(r.Dealer.Handlers(), r.internalSession)
+
+	return r, nil
+}
+
+func (r *Realm) Name() URI {
+	return r.name
+}
+
+// allowsAuthMethod checks realm accepted authmethods, void allows all
+func (r *Realm) allowsAuthMethod(method string) bool {
+	if len(r.authMethods) == 0 {
+		return true
+	}
+
+	return hasAuthMethod(r.authMethods, method)
+}
+
+// Terminate closes realm sessions and stops meta events handling
+func (r *Realm) Terminate() {
+	r.metaEvents.Terminate()
+	if a, ok := r.authorizer.(*ruleAuthorizer); ok {
+		a.Terminate()
+	}
+	if r.eventStore != nil {
+		if err := r.eventStore.Close(); err != nil {
+			log.Println("Error closing event store ", err)
+		}
+	}
+	close(r.exit)
+	log.Println("Realm terminated ", r.name)
+}
+
+func (r *Realm) register(p *Session) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.sessions[p.ID()]; ok {
+		return fmt.Errorf("Peer %s already registered", p.ID())
+	}
+	log.Println("registering ", p.ID(), " on realm ", r.name)
+	r.sessions[p.ID()] = p
+
+	return nil
+}
+
+func (r *Realm) unRegister(p *Session) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.sessions[p.ID()]; !ok {
+		return fmt.Errorf("Peer %s not registered", p.ID())
+	}
+
+	delete(r.sessions, p.ID())
+	log.Println("unregistering ", p.ID(), " from realm ", r.name)
+	return nil
+}
+
+func (r *Realm) totalSessions() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return len(r.sessions)
+}
@@ -0,0 +1,255 @@
+package core
+
+import (
+	"fmt"
+	"log"
+)
+
+func (r *Realm) Handlers() map[URI]Handler {
+	return map[URI]Handler{
+		"wampire.session.list":          r.listSessions,
+		"wampire.session.count":         r.countSessions,
+		"wampire.session.get":           r.getSession,
+		"wamp.session.list":             r.metaListSessions,
+		"wamp.session.kill":             r.killSession,
+		"wamp.session.kill_by_authid":   r.killByAuthID,
+		"wamp.session.kill_by_authrole": r.killByAuthRole,
+		"wamp.session.kill_all":         r.killAll,
+	}
+}
+
+func (r *Realm) listSessions(msg Message) (Message, error) {
+	r.mutex.RLock()
+	sessions := r.sessions
+	r.mutex.RUnlock()
+
+	list := []interface{}{}
+	for peerId, _ := range sessions {
+		list = append(list, peerId)
+	}
+
+	inv := msg.(*Invocation)
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: list,
+	}, nil
+
+}
+
+func (r *Realm) countSessions(msg Message) (Message, error) {
+	r.mutex.RLock()
+	total := len(r.sessions)
+	r.mutex.RUnlock()
+	inv := msg.(*Invocation)
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{total},
+	}, nil
+}
+
+func (r *Realm) getSession(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	if len(inv.Arguments) < 1 {
+		error := "Void ID argument on get session"
+		log.Println(error)
+		return nil, fmt.Errorf(error)
+	}
+
+	r.mutex.RLock()
+	s, ok := r.sessions[PeerID(inv.Arguments[0].(string))]
+	r.mutex.RUnlock()
+
+	if !ok {
+		error := "Router session ID %d not found "
+		log.Println(error)
+		return nil, fmt.Errorf(error)
+	}
+
+	subs := map[string]interface{}{}
+	for id, topic := range s.getSubscriptions() {
+		subs[fmt.Sprintf("%d", id)] = topic
+	}
+	regs := map[string]interface{}{}
+	for id, uri := range s.getRegistrations() {
+		regs[fmt.Sprintf("%d", id)] = uri
+	}
+	kw := map[string]interface{}{
+		"subscriptions": subs,
+		"registrations": regs,
+		"initTs":        s.initTs,
+	}
+
+	return &Yield{
+		Request:     inv.Request,
+		ArgumentsKw: kw,
+	}, nil
+}
+
+// metaListSessions returns session ids, optionally filtered by authroles list argument
+func (r *Realm) metaListSessions(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	var roles map[string]bool
+	if len(inv.Arguments) > 0 && inv.Arguments[0] != nil {
+		list, ok := inv.Arguments[0].([]interface{})
+		if !ok {
+			return nil, newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid authroles filter %v", inv.Arguments[0]))
+		}
+		roles = map[string]bool{}
+		for _, role := range list {
+			if name, ok := role.(string); ok {
+				roles[name] = true
+			}
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	list := []interface{}{}
+	for _, s := range r.sessions {
+		if roles == nil || roles[s.authRole()] {
+			list = append(list, s.sessionID)
+		}
+	}
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{list},
+	}, nil
+}
+
+// killSession kills session id argument with reason and message keyword arguments
+func (r *Realm) killSession(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	if len(inv.Arguments) < 1 {
+		return nil, newHandlerError(URI("wamp.error.invalid_argument"), "Void session argument")
+	}
+	id, ok := toInt(inv.Arguments[0])
+	if !ok {
+		return nil, newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid session argument %v", inv.Arguments[0]))
+	}
+	reason, message, err := killReason(inv)
+	if err != nil {
+		return nil, err
+	}
+	if caller, _ := toInt(inv.Details["caller"]); caller == id {
+		return nil, newHandlerError(URI("wamp.error.no_such_session"), "Caller session cannot be killed")
+	}
+
+	killed := r.kill(reason, message, inv, func(s *Session) bool { return s.sessionID == ID(id) })
+	if len(killed) == 0 {
+		return nil, newHandlerError(URI("wamp.error.no_such_session"))
+	}
+
+	return &Yield{Request: inv.Request}, nil
+}
+
+// killByAuthID kills sessions with authid argument but caller, returns killed session ids
+func (r *Realm) killByAuthID(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	authID, err := stringArgument(inv, "authid")
+	if err != nil {
+		return nil, err
+	}
+	reason, message, err := killReason(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	killed := r.kill(reason, message, inv, func(s *Session) bool { return s.authID() == authID })
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{killed},
+	}, nil
+}
+
+// killByAuthRole kills sessions with authrole argument but caller, returns killed sessions count
+func (r *Realm) killByAuthRole(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	authRole, err := stringArgument(inv, "authrole")
+	if err != nil {
+		return nil, err
+	}
+	reason, message, err := killReason(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	killed := r.kill(reason, message, inv, func(s *Session) bool { return s.authRole() == authRole })
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{len(killed)},
+	}, nil
+}
+
+// killAll kills all sessions but caller, returns killed sessions count
+func (r *Realm) killAll(msg Message) (Message, error) {
+	inv := msg.(*Invocation)
+	reason, message, err := killReason(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	killed := r.kill(reason, message, inv, func(s *Session) bool { return true })
+
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{len(killed)},
+	}, nil
+}
+
+// kill sends goodbye to matching sessions, caller session is skipped,
+// sessions are collected under lock and killed after releasing it
+func (r *Realm) kill(reason URI, message string, inv *Invocation, match func(*Session) bool) []interface{} {
+	caller, _ := toInt(inv.Details["caller"])
+
+	r.mutex.RLock()
+	sessions := []*Session{}
+	for _, s := range r.sessions {
+		if s.sessionID == ID(caller) || !match(s) {
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	r.mutex.RUnlock()
+
+	killed := []interface{}{}
+	for _, s := range sessions {
+		log.Println("Killing session ", s.ID(), reason)
+		s.kill(reason, message)
+		killed = append(killed, s.sessionID)
+	}
+
+	return killed
+}
+
+// killReason returns reason and message keyword arguments, reason defaults to wamp.close.killed
+func killReason(inv *Invocation) (URI, string, error) {
+	reason := URI("wamp.close.killed")
+	if v, ok := inv.ArgumentsKw["reason"]; ok {
+		r, ok := v.(string)
+		if !ok || r == "" {
+			return "", "", newHandlerError(URI("wamp.error.invalid_uri"), fmt.Sprintf("Invalid reason %v", v))
+		}
+		reason = URI(r)
+	}
+	message, _ := inv.ArgumentsKw["message"].(string)
+
+	return reason, message, nil
+}
+
+func stringArgument(inv *Invocation, name string) (string, error) {
+	if len(inv.Arguments) < 1 {
+		return "", newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Void %s argument", name))
+	}
+	v, ok := inv.Arguments[0].(string)
+	if !ok {
+		return "", newHandlerError(URI("wamp.error.invalid_argument"), fmt.Sprintf("Invalid %s argument %v", name, inv.Arguments[0]))
+	}
+
+	return v, nil
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+var testRealm *Realm
+
+func TestListSessions(t *testing.T) {
+	registerSessions()
+	invocation := &Invocation{
+		Request: ID(1234),
+	}
+	yield, err := testRealm.listSessions(invocation)
+	if err != nil {
+		t.Error("Error on listSessions invocation ", err)
+	}
+	if len(yield.(*Yield).Arguments) != 3 {
+		t.Error("Unexpected Yield arguments result ", yield.(*Yield).Arguments)
+	}
+
+	//Avoid failing, result may come disordered
+	for _, v := range yield.(*Yield).Arguments {
+		if v.(PeerID) == PeerID("fakeSession_0") {
+			continue
+		}
+		if v.(PeerID) == PeerID("fakeSession_1") {
+			continue
+		}
+		if v.(PeerID) == PeerID("fakeSession_2") {
+			continue
+		}
+		t.Error("Unexpected Yield arguments result ", yield.(*Yield).Arguments, v)
+	}
+}
+
+func TestCountSessions(t *testing.T) {
+	registerSessions()
+	invocation := &Invocation{
+		Request: ID(1234),
+	}
+	yield, err := testRealm.countSessions(invocation)
+	if err != nil {
+		t.Error("Error on listSessions invocation ", err)
+	}
+
+	if yield.(*Yield).Arguments[0].(int) != 3 {
+		t.Error("Unexpected Yield arguments result ", yield.(*Yield).Arguments)
+	}
+}
+
+func TestGetSessions(t *testing.T) {
+	registerSessions()
+	invocation := &Invocation{
+		Request:   ID(1234),
+		Arguments: []interface{}{"fakeSession_0"},
+	}
+	yield, err := testRealm.getSession(invocation)
+	if err != nil {
+		t.Error("Error on listSessions invocation ", err)
+	}
+
+	v, ok := yield.(*Yield).ArgumentsKw["subscriptions"]
+	if !ok {
+		t.Error("Unexpected Yield subscriptions ArgumentsKW not found ", yield.(*Yield).ArgumentsKw)
+	}
+	if len(v.(map[string]interface{})) != 0 {
+		t.Error("Unexpected Yield subscriptions lenght", yield.(*Yield).ArgumentsKw)
+	}
+	v, ok = yield.(*Yield).ArgumentsKw["registrations"]
+	if !ok {
+		t.Error("Unexpected Yield registrations ArgumentsKW not found ", yield.(*Yield).ArgumentsKw)
+	}
+	if len(v.(map[string]interface{})) != 0 {
+		t.Error("Unexpected Yield registrations lenght ", yield.(*Yield).ArgumentsKw)
+	}
+}
+
+func TestKillSessions(t *testing.T) {
+	registerSessions()
+	roles := []string{"admin", "worker", "worker"}
+	sessions := map[ID]*Session{}
+	for _, s := range testRealm.sessions {
+		i := len(sessions)
+		s.sessionID = ID(i + 1)
+		s.identity = &Identity{AuthID: fmt.Sprintf("user%d", i), AuthRole: roles[i]}
+		sessions[s.sessionID] = s
+	}
+
+	yield, _ := testRealm.metaListSessions(&Invocation{Request: ID(1), Arguments: []interface{}{[]interface{}{"worker"}}})
+	if list := yield.(*Yield).Arguments[0].([]interface{}); len(list) != 2 {
+		t.Error("Unexpected filtered session list ", list)
+	}
+
+	// caller worker session is not killed
+	inv := &Invocation{
+		Request:     ID(2),
+		Arguments:   []interface{}{"worker"},
+		ArgumentsKw: map[string]interface{}{"reason": "com.myapp.maintenance", "message": "bye"},
+		Details:     map[string]interface{}{"caller": ID(2)},
+	}
+	yield, err := testRealm.killByAuthRole(inv)
+	if err != nil || yield.(*Yield).Arguments[0] != 1 {
+		t.Fatal("Unexpected killed sessions ", yield, err)
+	}
+	killed := sessions[ID(3)]
+	goodbye, ok := (<-killed.Receive()).(*Goodbye)
+	if !ok || goodbye.Reason != URI("com.myapp.maintenance") || goodbye.Details["message"] != "bye" {
+		t.Error("Unexpected goodbye ", goodbye)
+	}
+	if reason := <-killed.killed; reason != URI("com.myapp.maintenance") {
+		t.Error("Unexpected kill reason ", reason)
+	}
+	select {
+	case <-sessions[ID(2)].killed:
+		t.Error("Unexpected caller session killed")
+	default:
+	}
+
+	yield, err = testRealm.killByAuthID(&Invocation{Request: ID(3), Arguments: []interface{}{"user0"}, Details: map[string]interface{}{}})
+	if err != nil || len(yield.(*Yield).Arguments[0].([]interface{})) != 1 {
+		t.Error("Unexpected killed sessions by authid ", yield, err)
+	}
+	if reason := <-sessions[ID(1)].killed; reason != URI("wamp.close.killed") {
+		t.Error("Unexpected default kill reason ", reason)
+	}
+
+	_, err = testRealm.killSession(&Invocation{Request: ID(4), Arguments: []interface{}{float64(12345)}, Details: map[string]interface{}{}})
+	if h, ok := err.(*handlerError); !ok || h.uri != URI("wamp.error.no_such_session") {
+		t.Error("Expected no such session error ", err)
+	}
+}
+
+func registerSessions() {
+	testRealm = &Realm{
+		sessions: make(map[PeerID]*Session),
+		mutex:    &sync.RWMutex{},
+	}
+
+	// register 3 sessions
+	for i := 0; i < 3; i++ {
+		fp := NewFakePeer(PeerID(fmt.Sprintf("fakeSession_%d", i)))
+		session := NewSession(fp)
+
+		testRealm.register(session)
+	}
+}
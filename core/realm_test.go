@@ -0,0 +1,47 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRealmsAreIsolated(t *testing.T) {
+	staging, _ := NewRealm(RealmConfig{Name: URI("staging")})
+	production, _ := NewRealm(RealmConfig{Name: URI("production")})
+	defer staging.Terminate()
+	defer production.Terminate()
+
+	sessionA := NewSession(NewFakePeer(PeerID("PeerA")))
+	sessionB := NewSession(NewFakePeer(PeerID("PeerB")))
+	staging.register(sessionA)
+	production.register(sessionB)
+
+	// Session B subscribes foo on production realm
+	production.Subscribe(&Subscribe{Request: ID(123), Topic: Topic("foo")}, sessionB)
+	r := <-sessionB.Receive()
+	if r.MsgType() != SUBSCRIBED {
+		t.Error("Error subscribing ", r.MsgType())
+	}
+
+	// Session A publishes foo on staging realm, no staging subscribers
+	staging.Publish(&Publish{Request: ID(9999), Topic: Topic("foo"), Options: map[string]interface{}{"acknowledge": true}}, sessionA)
+	r = <-sessionA.Receive()
+	if r.MsgType() != PUBLISHED {
+		t.Error("Unexpected publish response ", r.MsgType())
+	}
+	select {
+	case r := <-sessionB.Receive():
+		t.Error("Unexpected event from other realm ", r.MsgType())
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if staging.totalSessions() != 1 || production.totalSessions() != 1 {
+		t.Error("Unexpected realm sessions size")
+	}
+}
+
+func TestNewRealmInvalidDisclosurePolicy(t *testing.T) {
+	if _, err := NewRealm(RealmConfig{Name: URI("foo"), Disclosure: "sometimes"}); err == nil {
+		t.Error("Expected invalid disclosure policy error")
+	}
+}
@@ -10,46 +10,43 @@ import (
 type Router interface {
 	Accept(p Peer) error
 	Terminate()
-	SetAuthenticator(a Authenticator)
+	SetAuthenticators(a ...Authenticator)
 }
 
 type DefaultRouter struct {
-	sessions map[PeerID]*Session
-	Broker
-	Dealer
+	realms          map[URI]*Realm
 	exit            chan struct{}
 	mutex           *sync.RWMutex
-	auth            Authenticator
-	internalSession *inSession
-	metaEvents      SessionMetaEventHandler
+	authenticators  []Authenticator
+	callTimeout     int
+	managementRealm URI // realm exposing realm management procedures
 }
 
-type Authenticator func(Message) bool
-
-func NewRouter() *DefaultRouter {
-	internalSession := newInSession()
-	m := NewSessionMetaEventsHandler()
+func NewRouter(c *Config) *DefaultRouter {
 	r := &DefaultRouter{
-		sessions:        make(map[PeerID]*Session),
-		Broker:          NewBroker(m),
-		Dealer:          NewDealer(m),
+		realms:          make(map[URI]*Realm),
 		exit:            make(chan struct{}),
 		mutex:           &sync.RWMutex{},
-		internalSession: internalSession,
-		metaEvents:      m,
+		authenticators:  []Authenticator{NewAnonymousAuthenticator(ANONYMOUS)},
+		callTimeout:     c.CallTimeout,
+		managementRealm: c.ManagementRealm,
+	}
+	if r.managementRealm == "" {
+		r.managementRealm = defaultRealm
+	}
+	if len(c.Authenticators) > 0 {
+		chain, err := NewAuthenticators(c.Authenticators)
+		if err != nil {
+			log.Println("Error creating authenticators, rejecting all sessions ", err)
+		}
+		r.authenticators = chain
 	}
 
-	// Handle Session Meta Events
-	go m.Consume(r)
-
-	// Register in session procedures
-	r.Dealer.RegisterSessionHandlers(internalSession.Handlers(), internalSession)
-	r.Dealer.RegisterSessionHandlers(r.Handlers(), internalSession)
-	r.Dealer.RegisterSessionHandlers(r.Broker.Handlers(), internalSession)
-	r.Dealer.RegisterSessionHandlers(r.Dealer.Handlers(), internalSession)
-
-	//Handle internal Session
-	go r.handleSession(internalSession.session)
+	for _, rc := range c.Realms {
+		if _, err := r.CreateRealm(rc); err != nil {
+			log.Println("Error creating realm ", err)
+		}
+	}
 
 	return r
 }
@@ -67,21 +64,45 @@ func (r *DefaultRouter) Accept(p Peer) error {
 			return fmt.Errorf(err)
 		}
 
-		response, auth, err := r.authenticate(h)
-		if err != nil {
-			log.Println("Error authenticating")
+		realm, ok := r.Realm(h.Realm)
+		if !ok {
+			log.Println("Realm not found, abort ", h.Realm)
+			p.Send(&Abort{
+				Details: map[string]interface{}{"message": "The realm does not exist."},
+				Reason:  URI("wamp.error.no_such_realm"),
+			})
+
+			return fmt.Errorf("Realm %s not found", h.Realm)
 		}
-		p.Send(response)
-		if !auth {
-			log.Println("Authorization denegated, abort")
+
+		sessionID := NewId()
+		identity, err := r.authenticate(h, p, realm, sessionID)
+		if err != nil {
+			log.Println("Authentication failed, abort ", err)
+			e, ok := err.(*authError)
+			if !ok {
+				e = &authError{URI("wamp.error.authentication_failed"), err.Error()}
+			}
+			p.Send(e.abort())
 
 			return nil
 		}
 
+		details := r.defaultDetails()
+		for k, v := range identity.details() {
+			details[k] = v
+		}
+		p.Send(&Welcome{
+			Id:      sessionID,
+			Details: details,
+		})
+
 		session := NewSession(p)
-		r.register(session)
+		session.identity = identity
+		session.sessionID = sessionID
+		realm.register(session)
 
-		go r.handleSession(session)
+		go r.handleSession(session, realm)
 
 		return nil
 	case <-timeout.C:
@@ -92,7 +113,11 @@ func (r *DefaultRouter) Accept(p Peer) error {
 }
 
 func (r *DefaultRouter) Terminate() {
-	r.metaEvents.Terminate()
+	r.mutex.RLock()
+	for _, realm := range r.realms {
+		realm.Terminate()
+	}
+	r.mutex.RUnlock()
 	close(r.exit)
 	log.Println("Router terminated!")
 
@@ -102,49 +127,152 @@ func (r *DefaultRouter) Terminate() {
 
 }
 
-func (r *DefaultRouter) SetAuthenticator(a Authenticator) {
-	r.auth = a
+// SetAuthenticators replaces router ordered authentication chain
+func (r *DefaultRouter) SetAuthenticators(a ...Authenticator) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.authenticators = a
+}
+
+// CreateRealm adds a new realm to the router registry
+func (r *DefaultRouter) CreateRealm(c RealmConfig) (*Realm, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.realms[c.Name]; ok {
+		return nil, fmt.Errorf("Realm %s already exists", c.Name)
+	}
+	if c.CallTimeout == 0 {
+		c.CallTimeout = r.callTimeout
+	}
+
+	realm, err := NewRealm(c)
+	if err != nil {
+		return nil, err
+	}
+	// realm management is only exposed on management realm
+	if c.Name == r.managementRealm {
+		realm.Dealer.RegisterSessionHandlers(r.Handlers(), realm.internalSession)
+	}
+	r.realms[c.Name] = realm
+
+	//Handle realm internal Session
+	go r.handleSession(realm.internalSession.session, realm)
+	log.Println("Realm created ", c.Name)
+
+	return realm, nil
+}
+
+// DeleteRealm removes realm from router registry, closing its sessions
+func (r *DefaultRouter) DeleteRealm(name URI) error {
+	r.mutex.Lock()
+	realm, ok := r.realms[name]
+	if !ok {
+		r.mutex.Unlock()
+		return fmt.Errorf("Realm %s not found", name)
+	}
+	delete(r.realms, name)
+	r.mutex.Unlock()
+
+	realm.Terminate()
+
+	return nil
+}
+
+func (r *DefaultRouter) Realm(name URI) (*Realm, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	realm, ok := r.realms[name]
+
+	return realm, ok
 }
 
-func (r *DefaultRouter) authenticate(msg Message) (Message, bool, error) {
-	var auth bool = true
-	if r.auth != nil {
-		auth = r.auth(msg)
+// authenticate tries chain authenticators on HELLO authmethods order,
+// allowed by realm, falling back to next authmethod when failing
+// before challenging the peer
+func (r *DefaultRouter) authenticate(h *Hello, p Peer, realm *Realm, sessionID ID) (*Identity, error) {
+	methods := authMethods(h)
+	err := unsupportedAuthMethods(methods)
+	for _, auth := range r.realmAuthenticators(methods, realm) {
+		challenge, e := auth.Challenge(h, p, sessionID)
+		if e != nil {
+			log.Println("Authmethod ", auth.AuthMethod(), " challenge failed ", e)
+			err = e
+			continue
+		}
+		if challenge == nil {
+			identity, e := auth.Authenticate(h, p, nil, nil)
+			if e != nil {
+				log.Println("Authmethod ", auth.AuthMethod(), " failed ", e)
+				err = e
+				continue
+			}
+			return identity, nil
+		}
+
+		p.Send(challenge)
+		authenticate, err := waitAuthenticate(p)
+		if err != nil {
+			return nil, err
+		}
+
+		return auth.Authenticate(h, p, challenge, authenticate)
 	}
-	if !auth {
-		// @TODO: needs real response
-		return &Abort{
-			Details: map[string]interface{}{"message": "The realm does not exist."},
-			Reason:  URI("wamp.error.no_such_realm"),
-		}, false, nil
+
+	return nil, err
+}
+
+// realmAuthenticators returns chain authenticators on methods order allowed by realm
+func (r *DefaultRouter) realmAuthenticators(methods []string, realm *Realm) []Authenticator {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	auths := []Authenticator{}
+	for _, m := range methods {
+		if !realm.allowsAuthMethod(m) {
+			continue
+		}
+		for _, a := range r.authenticators {
+			if a.AuthMethod() == m {
+				auths = append(auths, a)
+				break
+			}
+		}
 	}
 
-	return &Welcome{
-		Id:      NewId(),
-		Details: r.defaultDetails(),
-	}, true, nil
+	return auths
 }
 
-func (r *DefaultRouter) handleSession(s *Session) {
+func (r *DefaultRouter) handleSession(s *Session, realm *Realm) {
+	reason := URI("wamp.close.transport_lost")
 	defer func() {
 		log.Println("Exit session handler from peer ", s.ID())
 		// remove session subscriptions
 		for sid, topic := range s.subscriptions {
 			log.Printf("Unsubscribe sid %d on topic %s \n", sid, topic)
 			u := &Unsubscribe{Request: NewId(), Subscription: sid}
-			go r.Broker.UnSubscribe(u, s)
+			go realm.Broker.UnSubscribe(u, s)
 			time.Sleep(time.Second)
 		}
+		// remove session registrations
+		realm.Dealer.UnregisterSession(s)
 		// Fire on_leave Session Meta Event
-		r.metaEvents.Fire(s.ID(), URI("wampire.session.on_leave"), map[string]interface{}{})
+		realm.metaEvents.Fire(
+			s.ID(),
+			Topic("wamp.session.on_leave"),
+			[]interface{}{s.sessionID, s.authID(), s.authRole()},
+			map[string]interface{}{"reason": reason},
+		)
 		//unregister session from router
-		r.unRegister(s)
+		realm.unRegister(s)
 		//exit session
 		s.Terminate()
 	}()
 
 	//Fire on_join Session Meta Event
-	r.metaEvents.Fire(s.ID(), URI("wampire.session.on_join"), map[string]interface{}{})
+	realm.metaEvents.Fire(s.ID(), Topic("wamp.session.on_join"), []interface{}{s.details()}, nil)
 
 	for {
 		select {
@@ -157,34 +285,35 @@ func (r *DefaultRouter) handleSession(s *Session) {
 			switch msg.(type) {
 			case *Goodbye:
 				log.Println("Received Goodbye, exit handle session")
+				reason = msg.(*Goodbye).Reason
 				return
 			case *Publish:
 				log.Println("Received Publish on topic ", msg.(*Publish).Topic)
-				go r.Broker.Publish(msg, s)
+				go realm.Broker.Publish(msg, s)
 			case *Subscribe:
 				log.Println("Received Subscribe", msg.(*Subscribe).Topic)
-				go r.Broker.Subscribe(msg, s)
+				go realm.Broker.Subscribe(msg, s)
 			case *Unsubscribe:
 				log.Println("Received Unubscribe")
-				go r.Broker.UnSubscribe(msg, s)
+				go realm.Broker.UnSubscribe(msg, s)
 			case *Call:
 				log.Println("Received Call ", msg.(*Call).Procedure)
-				go r.Dealer.Call(msg, s)
+				go realm.Dealer.Call(msg, s)
 			case *Cancel:
 				log.Println("Received Cancel, forward this to requester on dealer ")
-				go r.Dealer.Cancel(msg, s)
+				go realm.Dealer.Cancel(msg, s)
 			case *Yield:
 				log.Println("Received Yield, forward this to dealer ", msg.(*Yield))
-				go r.Dealer.Yield(msg, s)
-			case *Interrupt:
-				log.Println("Received Interrupt, forward this to dealer ", msg.(*Interrupt))
-				go r.Dealer.Interrupt(msg, s)
+				go realm.Dealer.Yield(msg, s)
+			case *Error:
+				log.Println("Received Error, forward this to dealer ", msg.(*Error).Error)
+				go realm.Dealer.Error(msg, s)
 			case *Register:
 				log.Println("Received Register ", msg.(*Register).Procedure)
-				go r.Dealer.Register(msg, s)
+				go realm.Dealer.Register(msg, s)
 			case *Unregister:
 				log.Println("Received Unregister")
-				go r.Dealer.Unregister(msg, s)
+				go realm.Dealer.Unregister(msg, s)
 
 			//[WIP] Unexpected messages
 			case *Invocation:
@@ -196,46 +325,31 @@ func (r *DefaultRouter) handleSession(s *Session) {
 			default:
 				log.Println("Session unhandled message ", msg.MsgType())
 			}
+		case reason = <-s.killed:
+			log.Println("Session killed ", s.ID(), reason)
+			return
 		case <-r.exit:
 			log.Println("Shutting down session handler from peer ", s.ID())
+			reason = URI("wamp.close.system_shutdown")
+			return
+		case <-realm.exit:
+			log.Println("Shutting down session handler from deleted realm, peer ", s.ID())
+			reason = URI("wamp.close.close_realm")
 			return
 		}
 	}
 }
 
-func (r *DefaultRouter) register(p *Session) error {
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
-
-	if _, ok := r.sessions[p.ID()]; ok {
-		return fmt.Errorf("Peer %s already registered", p.ID())
-	}
-	log.Println("registering ", p.ID())
-	r.sessions[p.ID()] = p
-
-	return nil
-}
-
-func (r *DefaultRouter) unRegister(p *Session) error {
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
-
-	if _, ok := r.sessions[p.ID()]; !ok {
-		return fmt.Errorf("Peer %s not registered", p.ID())
-	}
-
-	delete(r.sessions, p.ID())
-	log.Println("unregistering ", p.ID())
-	return nil
-}
-
 // waitUntilVoid: waits until all sessions are closed
 func (r *DefaultRouter) waitUntilVoid() chan struct{} {
 	void := make(chan struct{})
 	go func() {
 		for {
+			sessions := 0
 			r.mutex.RLock()
-			sessions := len(r.sessions)
+			for _, realm := range r.realms {
+				sessions += realm.totalSessions()
+			}
 			r.mutex.RUnlock()
 			if sessions == 0 {
 				close(void)
@@ -264,34 +378,37 @@ func (r *DefaultRouter) defaultDetails() map[string]interface{} {
 					//"publication_trustlevels": true,
 					"pattern_based_subscription": true,
 					"subscription_revocation":    true,
-					//"event_history": true,
+					"event_history":              true,
 				},
 			},
 			"broker": map[string]interface{}{
 				"features": map[string]interface{}{
-					"publisher_identification": true,
-					/*					"pattern_based_subscription": true,
-										"subscription_meta_api": true,
-										"subscription_revocation": true,
-										"publisher_exclusion": true,
-										"subscriber_blackwhite_listing": true,*/
+					"publisher_identification":      true,
+					"pattern_based_subscription":    true,
+					"publisher_exclusion":           true,
+					"subscriber_blackwhite_listing": true,
+					"event_history":                 true,
+					"subscription_meta_api":         true,
+					"subscription_revocation":       true,
 				},
 			},
 			"dealer": map[string]interface{}{
 				"features": map[string]interface{}{
-					"caller_identification": true,
-					/*					"progressive_call_results": true,
-										"pattern_based_registration": true,
-										"registration_revocation": true,
-										"shared_registration": true,
-										"registration_meta_api": true,*/
+					"caller_identification":      true,
+					"pattern_based_registration": true,
+					"shared_registration":        true,
+					"call_timeout":               true,
+					"call_canceling":             true,
+					"progressive_call_results":   true,
+					"registration_meta_api":      true,
+					"registration_revocation":    true,
 				},
 			},
 			"caller": map[string]interface{}{
 				"features": map[string]interface{}{
-					"caller_identification": true,
-					//"call_timeout": true,
-					//"call_canceling": true,
+					"caller_identification":    true,
+					"call_timeout":             true,
+					"call_canceling":           true,
 					"progressive_call_results": true,
 				},
 			},
@@ -301,10 +418,10 @@ func (r *DefaultRouter) defaultDetails() map[string]interface{} {
 					//"call_trustlevels": true,
 					"pattern_based_registration": true,
 					"shared_registration":        true,
-					//"call_timeout": true,
-					//"call_canceling": true,
-					"progressive_call_results": true,
-					"registration_revocation":  true,
+					"call_timeout":               true,
+					"call_canceling":             true,
+					"progressive_call_results":   true,
+					"registration_revocation":    true,
 				},
 			},
 		},
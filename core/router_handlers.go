@@ -1,27 +1,25 @@
 package core
 
-import(
-	"log"
+import (
 	"fmt"
+	"log"
 )
 
 func (r *DefaultRouter) Handlers() map[URI]Handler {
 	return map[URI]Handler{
-		"wampire.session.list":  r.listSessions,
-		"wampire.session.count": r.countSessions,
-		"wampire.session.get":   r.getSession,
+		"wampire.realm.list":   r.listRealms,
+		"wampire.realm.create": r.createRealm,
+		"wampire.realm.delete": r.deleteRealm,
 	}
 }
 
-func (r *DefaultRouter) listSessions(msg Message) (Message, error) {
+func (r *DefaultRouter) listRealms(msg Message) (Message, error) {
 	r.mutex.RLock()
-	sessions := r.sessions
-	r.mutex.RUnlock()
-
 	list := []interface{}{}
-	for peerId, _ := range sessions {
-		list = append(list, peerId)
+	for name, _ := range r.realms {
+		list = append(list, name)
 	}
+	r.mutex.RUnlock()
 
 	inv := msg.(*Invocation)
 
@@ -29,55 +27,58 @@ func (r *DefaultRouter) listSessions(msg Message) (Message, error) {
 		Request:   inv.Request,
 		Arguments: list,
 	}, nil
-
 }
 
-func (r *DefaultRouter) countSessions(msg Message) (Message, error) {
-	r.mutex.RLock()
-	total := len(r.sessions)
-	r.mutex.RUnlock()
+func (r *DefaultRouter) createRealm(msg Message) (Message, error) {
 	inv := msg.(*Invocation)
+	name, err := realmFromArguments(inv)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.CreateRealm(RealmConfig{Name: name}); err != nil {
+		log.Println(err)
+		return nil, err
+	}
 
 	return &Yield{
 		Request:   inv.Request,
-		Arguments: []interface{}{total},
+		Arguments: []interface{}{name},
 	}, nil
 }
 
-func (r *DefaultRouter) getSession(msg Message) (Message, error) {
+func (r *DefaultRouter) deleteRealm(msg Message) (Message, error) {
 	inv := msg.(*Invocation)
-	if len(inv.Arguments) < 1 {
-		error := "Void ID argument on get session"
-		log.Println(error)
-		return nil, fmt.Errorf(error)
+	name, err := realmFromArguments(inv)
+	if err != nil {
+		return nil, err
+	}
+	if name == r.managementRealm {
+		return nil, fmt.Errorf("Management realm %s can not be deleted", name)
 	}
 
-	r.mutex.RLock()
-	s, ok := r.sessions[PeerID(inv.Arguments[0].(string))]
-	r.mutex.RUnlock()
+	if err := r.DeleteRealm(name); err != nil {
+		log.Println(err)
+		return nil, err
+	}
 
-	if !ok {
-		error := "Router session ID %d not found "
+	return &Yield{
+		Request:   inv.Request,
+		Arguments: []interface{}{name},
+	}, nil
+}
+
+func realmFromArguments(inv *Invocation) (URI, error) {
+	if len(inv.Arguments) < 1 {
+		error := "Void realm argument"
 		log.Println(error)
-		return nil, fmt.Errorf(error)
+		return "", fmt.Errorf(error)
 	}
 
-	subs := map[string]interface{}{}
-	for id, topic := range s.getSubscriptions() {
-		subs[fmt.Sprintf("%d", id)] = topic
-	}
-	regs := map[string]interface{}{}
-	for id, uri := range s.getRegistrations() {
-		regs[fmt.Sprintf("%d", id)] = uri
-	}
-	kw := map[string]interface{}{
-		"subscriptions": subs,
-		"registrations": regs,
-		"initTs":        s.initTs,
+	name, ok := inv.Arguments[0].(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("Invalid realm argument %v", inv.Arguments[0])
 	}
 
-	return &Yield{
-		Request:     inv.Request,
-		ArgumentsKw: kw,
-	}, nil
+	return URI(name), nil
 }
@@ -33,3 +33,109 @@ func TestBasicRouterAccept(t *testing.T) {
 			t.Error("Unexpected welcome ID details")
 		}*/
 }
+
+func TestRouterAcceptOnRealm(t *testing.T) {
+	r := NewRouter(DefaultConfig())
+	defer r.Terminate()
+
+	fp := NewFakePeer(PeerID("123"))
+	fp.Send(&Hello{Realm: defaultRealm, Details: map[string]interface{}{}})
+	if err := r.Accept(fp); err != nil {
+		t.Error("Unexpected error on accept ", err)
+	}
+
+	w := <-fp.Receive()
+	if w.MsgType() != WELCOME {
+		t.Error("Unexpected welcome response ", w.MsgType())
+	}
+
+	realm, ok := r.Realm(defaultRealm)
+	if !ok {
+		t.Fatal("Default realm not found")
+	}
+	if realm.totalSessions() != 1 {
+		t.Error("Unexpected realm sessions size ", realm.totalSessions())
+	}
+	fp.Send(&Goodbye{})
+}
+
+func TestRouterAcceptOnUnknownRealm(t *testing.T) {
+	r := NewRouter(DefaultConfig())
+	defer r.Terminate()
+
+	fp := NewFakePeer(PeerID("123"))
+	fp.Send(&Hello{Realm: URI("unknown"), Details: map[string]interface{}{}})
+	if err := r.Accept(fp); err == nil {
+		t.Error("Expected error on unknown realm")
+	}
+
+	a := <-fp.Receive()
+	if a.MsgType() != ABORT {
+		t.Fatal("Unexpected abort response ", a.MsgType())
+	}
+	if a.(*Abort).Reason != URI("wamp.error.no_such_realm") {
+		t.Error("Unexpected abort reason ", a.(*Abort).Reason)
+	}
+}
+
+func TestRouterCreateAndDeleteRealm(t *testing.T) {
+	r := NewRouter(&Config{})
+	defer r.Terminate()
+
+	if _, err := r.CreateRealm(RealmConfig{Name: URI("staging")}); err != nil {
+		t.Error("Unexpected error creating realm ", err)
+	}
+	if _, err := r.CreateRealm(RealmConfig{Name: URI("staging")}); err == nil {
+		t.Error("Expected error creating duplicated realm")
+	}
+
+	inv := &Invocation{Request: ID(1234)}
+	yield, err := r.listRealms(inv)
+	if err != nil {
+		t.Error("Unexpected error listing realms ", err)
+	}
+	if len(yield.(*Yield).Arguments) != 1 {
+		t.Error("Unexpected realm list ", yield.(*Yield).Arguments)
+	}
+
+	inv = &Invocation{Request: ID(1234), Arguments: []interface{}{"staging"}}
+	if _, err := r.deleteRealm(inv); err != nil {
+		t.Error("Unexpected error deleting realm ", err)
+	}
+	if _, ok := r.Realm(URI("staging")); ok {
+		t.Error("Realm not deleted")
+	}
+}
+
+func TestRealmManagementOnManagementRealm(t *testing.T) {
+	r := NewRouter(&Config{Realms: []RealmConfig{{Name: defaultRealm}, {Name: URI("tenantA")}, {Name: URI("tenantB")}}})
+	defer r.Terminate()
+
+	call := func(realm URI, name string) Message {
+		rl, _ := r.Realm(realm)
+		fp := NewFakePeer(PeerID("admin_" + name))
+		s := NewSession(fp)
+		s.identity = &Identity{AuthID: "admin", AuthRole: "admin"}
+		rl.Dealer.Call(&Call{Request: ID(1), Procedure: URI("wampire.realm.delete"), Arguments: []interface{}{name}}, s)
+
+		return <-fp.Receive()
+	}
+
+	// tenant sessions can not manage other realms
+	if e, ok := call(URI("tenantA"), "tenantB").(*Error); !ok || e.Error != URI("wamp.error.no_such_procedure") {
+		t.Error("Expected no such procedure deleting realm from tenant realm ", e)
+	}
+	if _, ok := r.Realm(URI("tenantB")); !ok {
+		t.Error("Unexpected realm deleted from tenant realm")
+	}
+
+	if e, ok := call(defaultRealm, string(defaultRealm)).(*Error); !ok {
+		t.Error("Expected error deleting management realm ", e)
+	}
+	if msg := call(defaultRealm, "tenantB"); msg.MsgType() != RESULT {
+		t.Error("Unexpected response deleting realm ", msg)
+	}
+	if _, ok := r.Realm(URI("tenantB")); ok {
+		t.Error("Realm not deleted from management realm")
+	}
+}
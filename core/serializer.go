@@ -4,9 +4,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/mitchellh/mapstructure"
+	"github.com/ugorji/go/codec"
 	"reflect"
 )
 
+// WebSocket subprotocols by serializer
+const (
+	JSON_SUBPROTOCOL    = "wamp.2.json"
+	MSGPACK_SUBPROTOCOL = "wamp.2.msgpack"
+	CBOR_SUBPROTOCOL    = "wamp.2.cbor"
+)
+
 type Serializer interface {
 	Serialize(Message) ([]byte, error)
 	Deserialize([]byte) (Message, error)
@@ -40,31 +48,98 @@ func (s *JSONSerializer) Deserialize(data []byte) (Message, error) {
 		return nil, err
 	}
 	if len(payload) <= 1 {
-		panic(payload)
+		return nil, fmt.Errorf("Invalid message payload %v", payload)
+	}
+
+	return s.ToMessage(payload)
+}
+
+// codecSerializer handles binary serializers through codec handles
+type codecSerializer struct {
+	Encoder
+	handle codec.Handle
+}
+
+func (s *codecSerializer) Serialize(m Message) ([]byte, error) {
+	var data []byte
+	err := codec.NewEncoderBytes(&data, s.handle).Encode(s.ToList(m))
+
+	return data, err
+}
+
+func (s *codecSerializer) Deserialize(data []byte) (Message, error) {
+	payload := []interface{}{}
+	err := codec.NewDecoderBytes(data, s.handle).Decode(&payload)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) <= 1 {
+		return nil, fmt.Errorf("Invalid message payload %v", payload)
 	}
+
 	return s.ToMessage(payload)
 }
 
+type MsgPackSerializer struct {
+	codecSerializer
+}
+
+func NewMsgPackSerializer() *MsgPackSerializer {
+	h := &codec.MsgpackHandle{}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	h.WriteExt = true
+
+	return &MsgPackSerializer{
+		codecSerializer{
+			Encoder: &defaultEncoder{},
+			handle:  h,
+		},
+	}
+}
+
+type CBORSerializer struct {
+	codecSerializer
+}
+
+func NewCBORSerializer() *CBORSerializer {
+	h := &codec.CborHandle{}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+
+	return &CBORSerializer{
+		codecSerializer{
+			Encoder: &defaultEncoder{},
+			handle:  h,
+		},
+	}
+}
+
 type defaultEncoder struct{}
 
 func (e *defaultEncoder) ToList(msg Message) []interface{} {
 	ret := []interface{}{int(msg.MsgType())}
 	val := reflect.ValueOf(msg).Elem()
 
-	// @TODO: Handle tag annotations
 	for i := 0; i < val.Type().NumField(); i++ {
-		/*		tag := val.Type().Field(i).Tag.Get("wamp")
-				log.Println("Tag is ", tag)
-				if strings.Contains(tag, "omitempty") || val.Field(i).Len() == 0 {
-					break
-				}*/
 		ret = append(ret, val.Field(i).Interface())
 	}
+
+	// trailing optional fields are not sent when void
+	for i := val.Type().NumField() - 1; i >= 0; i-- {
+		if val.Type().Field(i).Tag.Get("wamp") != "optional" || val.Field(i).Len() > 0 {
+			break
+		}
+		ret = ret[:len(ret)-1]
+	}
+
 	return ret
 }
 
 func (e *defaultEncoder) ToMessage(l []interface{}) (Message, error) {
-	msgType := MsgType(int(l[0].(float64)))
+	t, ok := toInt(l[0])
+	if !ok {
+		return nil, fmt.Errorf("Unsupported message type %v", l[0])
+	}
+	msgType := MsgType(t)
 	msg := msgType.NewMessage()
 	if msg == nil {
 		return nil, fmt.Errorf("Unsupported message format")
@@ -85,3 +160,21 @@ func (e *defaultEncoder) ToMessage(l []interface{}) (Message, error) {
 
 	return msg, err
 }
+
+// toInt converts numeric message types decoded from any serializer
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	case ID:
+		return int(n), true
+	default:
+		return 0, false
+	}
+}
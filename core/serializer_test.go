@@ -1,6 +1,9 @@
 package core
 
 import (
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+	"reflect"
 	"testing"
 )
 
@@ -130,3 +133,163 @@ func TestListToMessageOnVoidFields(t *testing.T) {
 		t.Error("Unexpected message Arguments")
 	}
 }
+
+func TestMsgPackSerializer(t *testing.T) {
+	msg := &Call{
+		Request:     ID(2332246451159040),
+		Options:     map[string]interface{}{"receive_progress": true},
+		Procedure:   URI("com.example.add2"),
+		Arguments:   []interface{}{2, 3.5, []byte{0x01, 0x02}},
+		ArgumentsKw: map[string]interface{}{"samples": []interface{}{1, 2, 3}},
+	}
+
+	s := NewMsgPackSerializer()
+	data, err := s.Serialize(msg)
+	if err != nil {
+		t.Fatal("Unexpected error serializing ", err)
+	}
+
+	rcvMessage, err := s.Deserialize(data)
+	if err != nil {
+		t.Fatal("Unexpected error deserializing ", err)
+	}
+
+	c, ok := rcvMessage.(*Call)
+	if !ok {
+		t.Fatal("Wrong type ", rcvMessage.MsgType())
+	}
+	if c.Request != msg.Request || c.Procedure != msg.Procedure {
+		t.Error("Unexpected call ", c)
+	}
+	if c.Options["receive_progress"] != true {
+		t.Error("Unexpected call options ", c.Options)
+	}
+	if c.Arguments[1] != 3.5 {
+		t.Error("Unexpected call arguments ", c.Arguments)
+	}
+	if b, ok := c.Arguments[2].([]byte); !ok || len(b) != 2 {
+		t.Error("Unexpected binary argument ", c.Arguments[2])
+	}
+	if len(c.ArgumentsKw["samples"].([]interface{})) != 3 {
+		t.Error("Unexpected call keyword arguments ", c.ArgumentsKw)
+	}
+}
+
+func TestSerializerFromSubprotocol(t *testing.T) {
+	s, payloadType := newSerializer(MSGPACK_SUBPROTOCOL)
+	if _, ok := s.(*MsgPackSerializer); !ok || payloadType != websocket.BinaryMessage {
+		t.Error("Unexpected msgpack serializer")
+	}
+	s, payloadType = newSerializer(CBOR_SUBPROTOCOL)
+	if _, ok := s.(*CBORSerializer); !ok || payloadType != websocket.BinaryMessage {
+		t.Error("Unexpected cbor serializer")
+	}
+	s, payloadType = newSerializer("")
+	if _, ok := s.(*JSONSerializer); !ok || payloadType != websocket.TextMessage {
+		t.Error("Unexpected default serializer")
+	}
+}
+
+func TestCBORSerializerRoundTripsAllMessageTypes(t *testing.T) {
+	details := map[string]interface{}{"foo": "bar"}
+	args := []interface{}{"foo", []byte{0x00, 0xff}}
+	kwArgs := map[string]interface{}{"payload": []byte{0x01, 0x02}, "name": "foo"}
+	messages := []Message{
+		&Hello{Realm: URI("realm1"), Details: details},
+		&Welcome{Id: ID(1), Details: details},
+		&Abort{Details: details, Reason: URI("wamp.error.no_such_realm")},
+		&Challenge{AuthMethod: WAMPCRA, Extra: details},
+		&Authenticate{Signature: "signature", Extra: details},
+		&Goodbye{Details: details, Reason: URI("wamp.close.normal")},
+		&Error{Type: CALL, Request: ID(2), Details: details, Error: URI("com.myapp.error"), Arguments: args, ArgumentsKw: kwArgs},
+		&Publish{Request: ID(3), Options: details, Topic: Topic("com.myapp.topic"), Arguments: args, ArgumentsKw: kwArgs},
+		&Published{Request: ID(4), Publication: ID(5)},
+		&Subscribe{Request: ID(5), Options: details, Topic: Topic("com.myapp.topic")},
+		&Subscribed{Request: ID(6), Subscription: ID(7)},
+		&Unsubscribe{Request: ID(8), Subscription: ID(9)},
+		&Unsubscribed{Request: ID(10)},
+		&Event{Subscription: ID(11), Publication: ID(12), Details: details, Arguments: args, ArgumentsKw: kwArgs},
+		&Call{Request: ID(13), Options: details, Procedure: URI("com.myapp.add"), Arguments: args, ArgumentsKw: kwArgs},
+		&Cancel{Request: ID(14), Options: details},
+		&Result{Request: ID(15), Details: details, Arguments: args, ArgumentsKw: kwArgs},
+		&Register{Request: ID(16), Options: details, Procedure: URI("com.myapp.add")},
+		&Registered{Request: ID(17), Registration: ID(18)},
+		&Unregister{Request: ID(19), Registration: ID(20)},
+		&Unregistered{Request: ID(21)},
+		&Invocation{Request: ID(22), Registration: ID(23), Details: details, Arguments: args, ArgumentsKw: kwArgs},
+		&Interrupt{Request: ID(24), Options: details},
+		&Yield{Request: ID(25), Options: details, Arguments: args, ArgumentsKw: kwArgs},
+	}
+
+	s := NewCBORSerializer()
+	for _, msg := range messages {
+		data, err := s.Serialize(msg)
+		if err != nil {
+			t.Error("Unexpected error serializing ", msg.MsgType(), err)
+			continue
+		}
+
+		rcvMessage, err := s.Deserialize(data)
+		if err != nil {
+			t.Error("Unexpected error deserializing ", msg.MsgType(), err)
+			continue
+		}
+
+		if !reflect.DeepEqual(msg, rcvMessage) {
+			t.Errorf("Unexpected round trip on %s: %#v", msg.MsgType(), rcvMessage)
+		}
+	}
+}
+
+func TestSerializerOmitsVoidRevocationDetails(t *testing.T) {
+	s := NewJSONSerializer()
+	data, err := s.Serialize(&Unsubscribed{Request: ID(10)})
+	if err != nil || string(data) != "[35,10]" {
+		t.Error("Unexpected unsubscribed payload ", string(data), err)
+	}
+	data, err = s.Serialize(&Unregistered{Request: ID(11)})
+	if err != nil || string(data) != "[67,11]" {
+		t.Error("Unexpected unregistered payload ", string(data), err)
+	}
+
+	// void payload fields keep being sent
+	payloads := map[string]Message{
+		"[50,1,{},null,null,\"\"]":    &Result{Request: ID(1), Details: map[string]interface{}{}},
+		"[36,2,3,{},null,null]":       &Event{Subscription: ID(2), Publication: ID(3), Details: map[string]interface{}{}},
+		"[8,48,4,{},\"e\",null,null]": &Error{Type: CALL, Request: ID(4), Details: map[string]interface{}{}, Error: URI("e")},
+	}
+	for expected, msg := range payloads {
+		data, err := s.Serialize(msg)
+		if err != nil || string(data) != expected {
+			t.Error("Unexpected payload ", string(data), " expected ", expected, err)
+		}
+	}
+
+	revoked := &Unregistered{Details: map[string]interface{}{"registration": float64(7), "reason": "wampire.registration.revoked"}}
+	data, err = s.Serialize(revoked)
+	if err != nil {
+		t.Fatal("Unexpected error serializing ", err)
+	}
+	msg, err := s.Deserialize(data)
+	if err != nil || !reflect.DeepEqual(msg, revoked) {
+		t.Errorf("Unexpected revocation round trip %s: %#v", string(data), msg)
+	}
+}
+
+func TestSerializersRejectShortPayloads(t *testing.T) {
+	j := NewJSONSerializer()
+	for _, data := range []string{"[]", "[1]", ""} {
+		if _, err := j.Deserialize([]byte(data)); err == nil {
+			t.Error("Expected error on short json payload ", data)
+		}
+	}
+
+	m := NewMsgPackSerializer()
+	for _, payload := range [][]interface{}{{}, {1}} {
+		var data []byte
+		codec.NewEncoderBytes(&data, m.handle).Encode(payload)
+		if _, err := m.Deserialize(data); err == nil {
+			t.Error("Expected error on short msgpack payload ", payload)
+		}
+	}
+}
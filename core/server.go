@@ -1,6 +1,7 @@
 package core
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -13,6 +14,9 @@ import (
 
 type Server struct {
 	port          int
+	rawSocketPort int
+	rawSocketPath string
+	tls           *TLSConfig
 	router        *DefaultRouter
 	httpCientPath string
 }
@@ -20,23 +24,27 @@ type Server struct {
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  4096,
 	WriteBufferSize: 4096,
-	Subprotocols:    []string{"wamp.2.json"},
+	Subprotocols:    []string{JSON_SUBPROTOCOL, MSGPACK_SUBPROTOCOL, CBOR_SUBPROTOCOL},
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
 }
 
-func NewServer(port int) *Server {
-	router := NewRouter()
+func NewServer(c *Config) *Server {
+	router := NewRouter(c)
 
 	return &Server{
-		port:          port,
+		port:          c.Port,
+		rawSocketPort: c.RawSocketPort,
+		rawSocketPath: c.RawSocketPath,
+		tls:           c.TLS,
 		router:        router,
 		httpCientPath: "",
 	}
 }
 
-func (s *Server) Run() {
+// Run serves websocket and rawsocket listeners, returns on listening errors
+func (s *Server) Run() error {
 	defer log.Println("Start EXIT!!!")
 	log.Println("Server Starting")
 
@@ -50,17 +58,56 @@ func (s *Server) Run() {
 		router.PathPrefix("/").Handler(htmlClient)
 	}
 
-	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
+	var tlsConfig *tls.Config
+	if s.tls != nil {
+		var err error
+		tlsConfig, err = s.tls.Load()
+		if err != nil {
+			log.Println("Server Error loading TLS config ", err)
+			return err
+		}
+	}
+
+	ln, err := listenTCP(s.port, tlsConfig)
 	if err != nil {
 		log.Println("Server Error Listening ", err)
-		return
+		return err
+	}
+
+	var rln net.Listener
+	if s.rawSocketPort != 0 {
+		rln, err = listenTCP(s.rawSocketPort, tlsConfig)
+		if err != nil {
+			log.Println("Server Error Listening rawsocket ", err)
+			ln.Close()
+			return err
+		}
+	}
+
+	if s.rawSocketPath != "" {
+		// remove stale unix socket from previous runs
+		os.Remove(s.rawSocketPath)
+		uln, err := net.Listen("unix", s.rawSocketPath)
+		if err != nil {
+			log.Println("Server Error Listening rawsocket unix socket ", err)
+			ln.Close()
+			if rln != nil {
+				rln.Close()
+			}
+			return err
+		}
+		go s.ServeRawSocket(uln)
+	}
+	if rln != nil {
+		go s.ServeRawSocket(rln)
 	}
 
 	err = http.Serve(ln, router)
 	if err != nil {
-		log.Panic("Server Error Serving ", err)
-		return
+		log.Println("Server Error Serving ", err)
 	}
+
+	return err
 }
 
 func (s *Server) Terminate() {
@@ -84,6 +131,7 @@ func (s *Server) ServeWs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	p := NewWebsockerPeer(ws, SERVER)
+	p.request = r
 	log.Println("Serve websocket connection, peer ", p.ID())
 	s.router.Accept(p)
 }
@@ -91,3 +139,39 @@ func (s *Server) ServeWs(w http.ResponseWriter, r *http.Request) {
 func (s *Server) SetHttpClient(path string) {
 	s.httpCientPath = path
 }
+
+// ServeRawSocket accepts rawsocket connections until listener is closed
+func (s *Server) ServeRawSocket(ln net.Listener) {
+	defer ln.Close()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Println("Rawsocket listener closed ", err)
+			return
+		}
+
+		go func(conn net.Conn) {
+			p, err := AcceptRawSocket(conn)
+			if err != nil {
+				log.Println("Rawsocket handshake error ", err)
+				conn.Close()
+				return
+			}
+			log.Println("Serve rawsocket connection, peer ", p.ID())
+			s.router.Accept(p)
+		}(conn)
+	}
+}
+
+// listenTCP listens on tcp port, TLS wrapped if tlsConfig is set
+func listenTCP(port int, tlsConfig *tls.Config) (net.Listener, error) {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+
+	return ln, nil
+}
@@ -4,14 +4,18 @@ import (
 	"fmt"
 	"github.com/gorilla/websocket"
 	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestServerConnectionHandlingAndShutDown(t *testing.T) {
 	log.SetFlags(log.Ldate | log.Ltime | log.Lmicroseconds | log.Lshortfile)
-	s := NewServer(8888)
+	s := NewServer(DefaultConfig())
 	go s.Run()
 
 	time.Sleep(time.Millisecond * 100)
@@ -34,6 +38,66 @@ func TestServerConnectionHandlingAndShutDown(t *testing.T) {
 	close(tstClientB.done)
 }
 
+func TestServerRunRawSocketListenError(t *testing.T) {
+	busy, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	free, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := free.Addr().(*net.TCPAddr).Port
+	free.Close()
+
+	c := DefaultConfig()
+	c.Port = port
+	c.RawSocketPort = busy.Addr().(*net.TCPAddr).Port
+	s := NewServer(c)
+	defer s.router.Terminate()
+
+	if err := s.Run(); err == nil {
+		t.Fatal("Expected error on busy rawsocket port")
+	}
+
+	// websocket listener is closed
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		t.Fatal("Unexpected websocket listener not closed ", err)
+	}
+	ln.Close()
+}
+
+func TestServerSubprotocolNegotiation(t *testing.T) {
+	s := NewServer(DefaultConfig())
+	defer s.router.Terminate()
+	ts := httptest.NewServer(http.HandlerFunc(s.ServeWs))
+	defer ts.Close()
+
+	dialer := &websocket.Dialer{Subprotocols: []string{MSGPACK_SUBPROTOCOL}}
+	conn, _, err := dialer.Dial(strings.Replace(ts.URL, "http", "ws", 1), nil)
+	if err != nil {
+		t.Fatal("Unexpected error dialing ", err)
+	}
+	if conn.Subprotocol() != MSGPACK_SUBPROTOCOL {
+		t.Fatal("Unexpected negotiated subprotocol ", conn.Subprotocol())
+	}
+
+	session := NewSession(NewWebsockerPeer(conn, CLIENT))
+	defer session.Terminate()
+	if _, ok := session.Peer.(*webSocketPeer).serializer.(*MsgPackSerializer); !ok {
+		t.Fatal("Unexpected client serializer")
+	}
+
+	session.Send(&Hello{Realm: defaultRealm, Details: map[string]interface{}{}})
+	r := <-session.Receive()
+	if r.MsgType() != WELCOME {
+		t.Error("Unexpected hello response ", r.MsgType())
+	}
+	session.Send(&Goodbye{Details: map[string]interface{}{}, Reason: URI("wamp.close.normal")})
+}
+
 type testClient struct {
 	session       *Session
 	subscriptions map[ID]bool
@@ -60,7 +124,7 @@ func NewTestClient(host string) *testClient {
 }
 
 func (c *testClient) handshake() error {
-	go c.session.Send(&Hello{Realm: "foo", Details: map[string]interface{}{"foo": "bar"}})
+	go c.session.Send(&Hello{Realm: defaultRealm, Details: map[string]interface{}{"foo": "bar"}})
 	r := <-c.session.Receive()
 	if r.MsgType() != WELCOME {
 		return fmt.Errorf("unexpected Hello response ", r.MsgType())
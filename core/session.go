@@ -9,6 +9,21 @@ import (
 
 type Handler func(Message) (Message, error)
 
+// handlerError replies handler failures with its error uri
+// instead of wamp.error.runtime_error
+type handlerError struct {
+	uri       URI
+	arguments []interface{}
+}
+
+func newHandlerError(uri URI, arguments ...interface{}) error {
+	return &handlerError{uri: uri, arguments: arguments}
+}
+
+func (e *handlerError) Error() string {
+	return fmt.Sprintf("%s %v", e.uri, e.arguments)
+}
+
 type Session struct {
 	Peer
 	subscriptions map[ID]Topic    // Topic subscriptions
@@ -16,6 +31,10 @@ type Session struct {
 	handlers      map[URI]Handler // Handlers by URI
 	mutex         *sync.RWMutex
 	initTs        time.Time
+	identity      *Identity
+	sessionID     ID           // WAMP session id from welcome
+	requests      *idGenerator // router requests to session ids
+	killed        chan URI     // router kill reason
 }
 
 func NewSession(p Peer) *Session {
@@ -26,6 +45,8 @@ func NewSession(p Peer) *Session {
 		handlers:      make(map[URI]Handler),
 		mutex:         &sync.RWMutex{},
 		initTs:        time.Now(),
+		requests:      newIdGenerator(),
+		killed:        make(chan URI, 1),
 	}
 }
 
@@ -73,10 +94,22 @@ func (s *Session) do(i *Invocation) error {
 		return nil
 	}
 
-	// On local handling
+	// On local handling, handler errors are replied as invocation errors
 	response, err := handler(i)
 	if err != nil {
-		return err
+		log.Println("Handler error on invocation ", i.Request, err)
+		e := &Error{
+			Type:      INVOCATION,
+			Request:   i.Request,
+			Details:   map[string]interface{}{},
+			Error:     URI("wamp.error.runtime_error"),
+			Arguments: []interface{}{err.Error()},
+		}
+		if h, ok := err.(*handlerError); ok {
+			e.Error = h.uri
+			e.Arguments = h.arguments
+		}
+		response = e
 	}
 
 	s.Send(response)
@@ -149,3 +182,64 @@ func (s *Session) getRegistrations() map[ID]URI {
 
 	return s.registrations
 }
+
+// kill sends goodbye with reason, session handler closes session on killed
+func (s *Session) kill(reason URI, message string) {
+	s.Send(&Goodbye{
+		Details: map[string]interface{}{"message": message},
+		Reason:  reason,
+	})
+	select {
+	case s.killed <- reason:
+	default:
+	}
+}
+
+// nextRequestId allocates session scope id on router requests
+func (s *Session) nextRequestId() ID {
+	return s.requests.next()
+}
+
+// disclose returns session identification details on caller or publisher key
+func (s *Session) disclose(key string) map[string]interface{} {
+	details := map[string]interface{}{key: s.sessionID}
+	if s.identity != nil {
+		details[key+"_authid"] = s.identity.AuthID
+		details[key+"_authrole"] = s.identity.AuthRole
+	}
+
+	return details
+}
+
+// details describes session identity and transport on meta events
+func (s *Session) details() map[string]interface{} {
+	details := map[string]interface{}{"session": s.sessionID}
+	if s.identity != nil {
+		for k, v := range s.identity.details() {
+			details[k] = v
+		}
+	}
+	if p, ok := s.Peer.(TransportPeer); ok {
+		details["transport"] = p.Transport()
+	}
+
+	return details
+}
+
+// authID returns session authenticated id, void on unauthenticated sessions
+func (s *Session) authID() string {
+	if s.identity == nil {
+		return ""
+	}
+
+	return s.identity.AuthID
+}
+
+// authRole returns session authenticated role, void on unauthenticated sessions
+func (s *Session) authRole() string {
+	if s.identity == nil {
+		return ""
+	}
+
+	return s.identity.AuthRole
+}
@@ -1 +1,22 @@
 package core
+
+import (
+	"testing"
+)
+
+func TestSessionDetails(t *testing.T) {
+	s := NewSession(NewFakePeer(PeerID("123")))
+	s.sessionID = ID(7)
+	s.identity = &Identity{AuthID: "alice", AuthRole: "user", AuthMethod: "ticket"}
+
+	details := s.details()
+	if details["session"] != ID(7) || details["authid"] != "alice" || details["authrole"] != "user" || details["authmethod"] != "ticket" {
+		t.Error("Unexpected session details ", details)
+	}
+	if _, ok := details["transport"]; ok {
+		t.Error("Unexpected transport details on fake peer")
+	}
+	if s.authID() != "alice" {
+		t.Error("Unexpected session authid ", s.authID())
+	}
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+const TLS = "tls"
+
+// TLSConfig enables TLS on server listeners, ClientCAFile enables
+// client certificate verification, optional unless RequireClientCert
+type TLSConfig struct {
+	CertFile          string `json:"cert_file"`
+	KeyFile           string `json:"key_file"`
+	ClientCAFile      string `json:"client_ca_file"`
+	RequireClientCert bool   `json:"require_client_cert"`
+}
+
+func (c *TLSConfig) Load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	if c.ClientCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := ioutil.ReadFile(c.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("No certificates found on CA bundle %s", c.ClientCAFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	if c.RequireClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+// TLSPeer exposes verified client certificate from TLS transports
+type TLSPeer interface {
+	ClientCertificate() *x509.Certificate
+}
+
+// verifiedCertificate returns leaf certificate from verified chains
+func verifiedCertificate(state *tls.ConnectionState) *x509.Certificate {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return state.VerifiedChains[0][0]
+}
+
+// CertificateMapper maps verified client certificate to session identity
+type CertificateMapper interface {
+	MapCertificate(cert *x509.Certificate) (*Identity, error)
+}
+
+// subjectMapper uses subject common name as authid, roles by common name
+type subjectMapper struct {
+	roles       map[string]string
+	defaultRole string
+}
+
+func NewSubjectMapper(roles map[string]string, defaultRole string) *subjectMapper {
+	return &subjectMapper{
+		roles:       roles,
+		defaultRole: defaultRole,
+	}
+}
+
+func (m *subjectMapper) MapCertificate(cert *x509.Certificate) (*Identity, error) {
+	authID := cert.Subject.CommonName
+	if authID == "" {
+		return nil, fmt.Errorf("Client certificate without subject common name")
+	}
+
+	role, ok := m.roles[authID]
+	if !ok {
+		role = m.defaultRole
+	}
+	if role == "" {
+		return nil, fmt.Errorf("No role found for client certificate %s", authID)
+	}
+
+	return &Identity{AuthID: authID, AuthRole: role}, nil
+}
+
+/** TLS authenticator, identity from verified client certificate **/
+type tlsAuthenticator struct {
+	mapper CertificateMapper
+}
+
+func NewTLSAuthenticator(m CertificateMapper) *tlsAuthenticator {
+	return &tlsAuthenticator{mapper: m}
+}
+
+func (a *tlsAuthenticator) AuthMethod() string {
+	return TLS
+}
+
+func (a *tlsAuthenticator) Challenge(h *Hello, p Peer, sessionID ID) (*Challenge, error) {
+	return nil, nil
+}
+
+func (a *tlsAuthenticator) Authenticate(h *Hello, p Peer, c *Challenge, auth *Authenticate) (*Identity, error) {
+	tp, ok := p.(TLSPeer)
+	if !ok || tp.ClientCertificate() == nil {
+		return nil, &authError{URI("wamp.error.authentication_failed"), "Verified client certificate not found"}
+	}
+
+	cert := tp.ClientCertificate()
+	identity, err := a.mapper.MapCertificate(cert)
+	if err != nil {
+		return nil, &authError{URI("wamp.error.authentication_failed"), err.Error()}
+	}
+	identity.AuthMethod = TLS
+	identity.AuthProvider = "x509"
+	identity.Extra = map[string]interface{}{
+		"subject": cert.Subject.String(),
+		"issuer":  cert.Issuer.String(),
+		"serial":  cert.SerialNumber.String(),
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMutualTLSAuthentication(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wampire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, caKey := newTestCertificate(t, "wampire-ca", nil, nil)
+	server, serverKey := newTestCertificate(t, "localhost", ca, caKey)
+	client, clientKey := newTestCertificate(t, "backend", ca, caKey)
+
+	config := &TLSConfig{
+		CertFile:          writeTestPEM(t, dir, "server.pem", "CERTIFICATE", server.Raw),
+		KeyFile:           writeTestPEM(t, dir, "server.key", "EC PRIVATE KEY", marshalTestKey(t, serverKey)),
+		ClientCAFile:      writeTestPEM(t, dir, "ca.pem", "CERTIFICATE", ca.Raw),
+		RequireClientCert: true,
+	}
+	tlsConfig, err := config.Load()
+	if err != nil {
+		t.Fatal("Unexpected error loading TLS config ", err)
+	}
+
+	s := NewServer(DefaultConfig())
+	defer s.router.Terminate()
+	s.router.SetAuthenticators(NewTLSAuthenticator(NewSubjectMapper(map[string]string{"backend": "service"}, "")))
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(s.ServeWs))
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	dialer := &websocket.Dialer{
+		TLSClientConfig: &tls.Config{
+			RootCAs: pool,
+			Certificates: []tls.Certificate{
+				{Certificate: [][]byte{client.Raw}, PrivateKey: clientKey},
+			},
+		},
+	}
+	conn, _, err := dialer.Dial(strings.Replace(ts.URL, "https", "wss", 1), nil)
+	if err != nil {
+		t.Fatal("Unexpected error dialing ", err)
+	}
+
+	session := NewSession(NewWebsockerPeer(conn, CLIENT))
+	defer session.Terminate()
+	session.Send(&Hello{
+		Realm:   defaultRealm,
+		Details: map[string]interface{}{"authmethods": []interface{}{TLS}},
+	})
+	r := <-session.Receive()
+	w, ok := r.(*Welcome)
+	if !ok {
+		t.Fatal("Unexpected hello response ", r.MsgType())
+	}
+	if w.Details["authid"] != "backend" || w.Details["authrole"] != "service" || w.Details["authmethod"] != TLS {
+		t.Error("Unexpected welcome details ", w.Details)
+	}
+	session.Send(&Goodbye{Details: map[string]interface{}{}, Reason: URI("wamp.close.normal")})
+}
+
+func TestMutualTLSRawSocketListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wampire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, caKey := newTestCertificate(t, "wampire-ca", nil, nil)
+	server, serverKey := newTestCertificate(t, "localhost", ca, caKey)
+	client, clientKey := newTestCertificate(t, "backend", ca, caKey)
+	rogueCA, rogueKey := newTestCertificate(t, "rogue-ca", nil, nil)
+	rogue, rogueClientKey := newTestCertificate(t, "backend", rogueCA, rogueKey)
+
+	config := &TLSConfig{
+		CertFile:          writeTestPEM(t, dir, "server.pem", "CERTIFICATE", server.Raw),
+		KeyFile:           writeTestPEM(t, dir, "server.key", "EC PRIVATE KEY", marshalTestKey(t, serverKey)),
+		ClientCAFile:      writeTestPEM(t, dir, "ca.pem", "CERTIFICATE", ca.Raw),
+		RequireClientCert: true,
+	}
+	tlsConfig, err := config.Load()
+	if err != nil {
+		t.Fatal("Unexpected error loading TLS config ", err)
+	}
+
+	s := NewServer(DefaultConfig())
+	defer s.router.Terminate()
+	s.router.SetAuthenticators(NewTLSAuthenticator(NewSubjectMapper(map[string]string{"backend": "service"}, "")))
+	ln, err := listenTCP(0, tlsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ServeRawSocket(ln)
+	defer ln.Close()
+	address := fmt.Sprintf("127.0.0.1:%d", ln.Addr().(*net.TCPAddr).Port)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	clientConfig := func(cert *x509.Certificate, key *ecdsa.PrivateKey) *tls.Config {
+		return &tls.Config{
+			RootCAs:      pool,
+			Certificates: []tls.Certificate{{Certificate: [][]byte{cert.Raw}, PrivateKey: key}},
+		}
+	}
+
+	// client certificate from untrusted CA is rejected
+	if _, err := DialRawSocketTLS(address, rawSocketJSON, clientConfig(rogue, rogueClientKey)); err == nil {
+		t.Error("Expected error dialing with untrusted client certificate")
+	}
+	if _, err := DialRawSocketTLS(address, rawSocketJSON, &tls.Config{RootCAs: pool}); err == nil {
+		t.Error("Expected error dialing without client certificate")
+	}
+
+	p, err := DialRawSocketTLS(address, rawSocketMsgPack, clientConfig(client, clientKey))
+	if err != nil {
+		t.Fatal("Unexpected error dialing ", err)
+	}
+	session := NewSession(p)
+	defer session.Terminate()
+	session.Send(&Hello{
+		Realm:   defaultRealm,
+		Details: map[string]interface{}{"authmethods": []interface{}{TLS}},
+	})
+	r := <-session.Receive()
+	w, ok := r.(*Welcome)
+	if !ok {
+		t.Fatal("Unexpected hello response ", r.MsgType())
+	}
+	if w.Details["authid"] != "backend" || w.Details["authrole"] != "service" || w.Details["authmethod"] != TLS {
+		t.Error("Unexpected welcome details ", w.Details)
+	}
+	session.Send(&Goodbye{Details: map[string]interface{}{}, Reason: URI("wamp.close.normal")})
+}
+
+func TestTLSAuthenticatorWithoutCertificate(t *testing.T) {
+	a := NewTLSAuthenticator(NewSubjectMapper(nil, "service"))
+	_, err := a.Authenticate(&Hello{}, NewFakePeer(PeerID("123")), nil, nil)
+	if err == nil {
+		t.Error("Expected error on peer without client certificate")
+	}
+}
+
+// newTestCertificate creates a self signed CA if parent is nil
+func newTestCertificate(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func marshalTestKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+}
+
+func writeTestPEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"strings"
+)
+
+// uriPattern identifies prefix and wildcard subscriptions and registrations
+type uriPattern struct {
+	match string
+	uri   URI
+}
+
+// uriTrie indexes pattern ids by uri keys, prefix patterns are keyed
+// by uri characters and wildcard patterns by uri components
+type uriTrie struct {
+	children map[string]*uriTrie
+	ids      map[ID]bool
+}
+
+func newURITrie() *uriTrie {
+	return &uriTrie{
+		children: make(map[string]*uriTrie),
+		ids:      make(map[ID]bool),
+	}
+}
+
+func (t *uriTrie) add(keys []string, id ID) {
+	node := t
+	for _, k := range keys {
+		child, ok := node.children[k]
+		if !ok {
+			child = newURITrie()
+			node.children[k] = child
+		}
+		node = child
+	}
+	node.ids[id] = true
+}
+
+// remove deletes id from keys path, pruning void nodes
+func (t *uriTrie) remove(keys []string, id ID) {
+	if len(keys) == 0 {
+		delete(t.ids, id)
+		return
+	}
+
+	child, ok := t.children[keys[0]]
+	if !ok {
+		return
+	}
+	child.remove(keys[1:], id)
+	if len(child.ids) == 0 && len(child.children) == 0 {
+		delete(t.children, keys[0])
+	}
+}
+
+// matchPrefix collects ids on every node along keys path
+func (t *uriTrie) matchPrefix(keys []string, ids map[ID]bool) {
+	node := t
+	for i := 0; ; i++ {
+		for id := range node.ids {
+			ids[id] = true
+		}
+		if i == len(keys) {
+			return
+		}
+		child, ok := node.children[keys[i]]
+		if !ok {
+			return
+		}
+		node = child
+	}
+}
+
+// matchWildcard collects ids on keys path length nodes, void
+// components match any key
+func (t *uriTrie) matchWildcard(keys []string, ids map[ID]bool) {
+	if len(keys) == 0 {
+		for id := range t.ids {
+			ids[id] = true
+		}
+		return
+	}
+
+	if child, ok := t.children[keys[0]]; ok {
+		child.matchWildcard(keys[1:], ids)
+	}
+	if keys[0] == "" {
+		return
+	}
+	if child, ok := t.children[""]; ok {
+		child.matchWildcard(keys[1:], ids)
+	}
+}
+
+func prefixKeys(uri string) []string {
+	keys := make([]string, len(uri))
+	for i := 0; i < len(uri); i++ {
+		keys[i] = uri[i : i+1]
+	}
+
+	return keys
+}
+
+func wildcardKeys(uri string) []string {
+	return strings.Split(uri, ".")
+}
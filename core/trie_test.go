@@ -0,0 +1,49 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestURITrieMatchPrefix(t *testing.T) {
+	trie := newURITrie()
+	trie.add(prefixKeys("com.myapp"), ID(1))
+	trie.add(prefixKeys("com.myapp.user"), ID(2))
+	trie.add(prefixKeys("com.other"), ID(3))
+	trie.add(prefixKeys(""), ID(4))
+
+	ids := map[ID]bool{}
+	trie.matchPrefix(prefixKeys("com.myapp.user.created"), ids)
+	if len(ids) != 3 || !ids[ID(1)] || !ids[ID(2)] || !ids[ID(4)] {
+		t.Error("Unexpected prefix matches ", ids)
+	}
+
+	trie.remove(prefixKeys("com.myapp.user"), ID(2))
+	ids = map[ID]bool{}
+	trie.matchPrefix(prefixKeys("com.myapp.user.created"), ids)
+	if len(ids) != 2 || ids[ID(2)] {
+		t.Error("Unexpected prefix matches after remove ", ids)
+	}
+}
+
+func TestURITrieMatchWildcard(t *testing.T) {
+	trie := newURITrie()
+	trie.add(wildcardKeys("com..user"), ID(1))
+	trie.add(wildcardKeys("com.myapp."), ID(2))
+	trie.add(wildcardKeys(".."), ID(3))
+	trie.add(wildcardKeys("com.myapp.user.created"), ID(4))
+
+	ids := map[ID]bool{}
+	trie.matchWildcard(wildcardKeys("com.myapp.user"), ids)
+	if len(ids) != 3 || !ids[ID(1)] || !ids[ID(2)] || !ids[ID(3)] {
+		t.Error("Unexpected wildcard matches ", ids)
+	}
+
+	for _, uri := range []string{"com..user", "com.myapp.", "..", "com.myapp.user.created"} {
+		for id := ID(1); id <= ID(4); id++ {
+			trie.remove(wildcardKeys(uri), id)
+		}
+	}
+	if len(trie.children) != 0 {
+		t.Error("Expected void trie after remove")
+	}
+}
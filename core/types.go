@@ -4,6 +4,7 @@ import (
 	"github.com/nu7hatch/gouuid"
 	"log"
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -43,8 +44,35 @@ func init() {
 	rand.Seed(time.Now().Unix())
 }
 
+// WAMP ids are integers in [1, 2^53]
+const maxId = 1 << 53
+
+// NewId returns a global scope id, random on [1, 2^53], used
+// on session and publication ids
 func NewId() ID {
-	return ID(rand.Int31())
+	return ID(rand.Int63n(maxId) + 1)
+}
+
+// idGenerator allocates router and session scope ids sequentially
+type idGenerator struct {
+	last  ID
+	mutex sync.Mutex
+}
+
+func newIdGenerator() *idGenerator {
+	return &idGenerator{}
+}
+
+func (g *idGenerator) next() ID {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.last == maxId {
+		g.last = 0
+	}
+	g.last++
+
+	return g.last
 }
 
 func NewStringId() PeerID {
@@ -134,6 +162,12 @@ func (t MsgType) String() string {
 		return "WELCOME"
 	case ABORT:
 		return "ABORT"
+	case CHALLENGE:
+		return "CHALLENGE"
+	case AUTHENTICATE:
+		return "AUTHENTICATE"
+	case GOODBYE:
+		return "GOODBYE"
 	case ERROR:
 		return "ERROR"
 	case PUBLISH:
@@ -300,9 +334,10 @@ func (msg *Goodbye) MsgType() MsgType {
 // [ERROR, REQUEST.Type|int, REQUEST.Request|id, Details|dict, Error|uri, Arguments|list]
 // [ERROR, REQUEST.Type|int, REQUEST.Request|id, Details|dict, Error|uri, Arguments|list, ArgumentsKw|dict]
 type Error struct {
+	Type        MsgType
 	Request     ID
-	Error       URI
 	Details     map[string]interface{}
+	Error       URI
 	Arguments   []interface{}          `wamp:"omitempty"`
 	ArgumentsKw map[string]interface{} `wamp:"omitempty"`
 }
@@ -328,7 +363,8 @@ func (msg *Publish) MsgType() MsgType {
 
 // [PUBLISHED, PUBLISH.Request|id, Publication|id]
 type Published struct {
-	Request ID
+	Request     ID
+	Publication ID
 }
 
 func (msg *Published) MsgType() MsgType {
@@ -379,8 +415,10 @@ func (msg *Unsubscribe) MsgType() MsgType {
   [35, 85346237]
 */
 // [UNSUBSCRIBED, UNSUBSCRIBE.Request|id]
+// [UNSUBSCRIBED, 0, Details|dict] on router revocation
 type Unsubscribed struct {
 	Request ID
+	Details map[string]interface{} `wamp:"optional"`
 }
 
 func (msg *Unsubscribed) MsgType() MsgType {
@@ -464,8 +502,10 @@ func (msg *Unregister) MsgType() MsgType {
 }
 
 // [UNREGISTERED, UNREGISTER.Request|id]
+// [UNREGISTERED, 0, Details|dict] on router revocation
 type Unregistered struct {
 	Request ID
+	Details map[string]interface{} `wamp:"optional"`
 }
 
 func (msg *Unregistered) MsgType() MsgType {
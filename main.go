@@ -14,7 +14,8 @@ func main() {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
 	//Parse config
-	port := flag.Int("port", 8888, "port")
+	port := flag.Int("port", 0, "port, overrides config port")
+	configPath := flag.String("config", "", "json config file path")
 	logOut := flag.Bool("log", false, "logger out path")
 	flag.Parse()
 
@@ -29,7 +30,19 @@ func main() {
 		defer f.Close()
 		log.SetOutput(f)
 	}
-	s := core.NewServer(*port)
+	config := core.DefaultConfig()
+	if *configPath != "" {
+		var err error
+		config, err = core.LoadConfig(*configPath)
+		if err != nil {
+			log.Fatal("error loading config: ", err)
+		}
+	}
+	if *port != 0 {
+		config.Port = *port
+	}
+
+	s := core.NewServer(config)
 	c := make(chan os.Signal, 1)
 
 	signal.Notify(
@@ -49,5 +62,7 @@ func main() {
 
 	// enable html Client
 	s.SetHttpClient("clients/htmlClient/")
-	s.Run()
+	if err := s.Run(); err != nil {
+		log.Fatal("Server error: ", err)
+	}
 }